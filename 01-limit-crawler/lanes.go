@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+// The priority classes of CrawlLanes, highest first.
+const (
+	Seed = iota
+	Sitemap
+	Shallow
+	Deep
+)
+
+var classNames = []string{"seed", "sitemap", "shallow", "deep"}
+
+// classWeights are the lane weights of the classes: while all of them
+// have URLs waiting, seeds get 8 of every 15 fetches and deep links 1.
+var classWeights = []int{8, 4, 2, 1}
+
+// NewClassLanes returns Lanes sharing limiter between the classes.
+func NewClassLanes(limiter ratelimit.Limiter, clock ratelimit.Clock) *ratelimit.Lanes {
+	return ratelimit.NewLanes(limiter, clock, classWeights...)
+}
+
+// class returns the priority class of url, found level links
+// away from the seed.
+func class(url string, level int) int {
+	switch {
+	case level == 0:
+		return Seed
+	case strings.Contains(url, "sitemap"):
+		return Sitemap
+	case level == 1:
+		return Shallow
+	default:
+		return Deep
+	}
+}
+
+// CrawlLanes is Crawl with every fetch waiting in the lane of its
+// priority class, so that under a tight budget seeds, sitemaps and
+// pages near the seed are fetched before deep links, without ever
+// starving them.
+func CrawlLanes(f Fetcher, url string, depth int, wg *sync.WaitGroup, lanes *ratelimit.Lanes) {
+	crawlLanes(f, url, 0, depth, wg, lanes)
+}
+
+func crawlLanes(f Fetcher, url string, level, depth int, wg *sync.WaitGroup, lanes *ratelimit.Lanes) {
+	defer wg.Done()
+
+	if depth <= 0 {
+		return
+	}
+
+	if err := lanes.Wait(context.Background(), class(url, level)); err != nil {
+		fmt.Println(err)
+		return
+	}
+	body, urls, err := f.Fetch(url)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("found: %s %q\n", url, body)
+
+	wg.Add(len(urls))
+	for _, u := range urls {
+		go crawlLanes(f, u, level+1, depth-1, wg, lanes)
+	}
+}
+
+// PrintLaneStats writes how long the fetches of each class
+// waited in their lane.
+func PrintLaneStats(w io.Writer, lanes *ratelimit.Lanes) {
+	for i, s := range lanes.Stats() {
+		if s.Events == 0 {
+			continue
+		}
+		mean := s.Waited / time.Duration(s.Events)
+		fmt.Fprintf(w, "%s: %d fetches, waited %v on average, %v at most\n", classNames[i], s.Events, mean, s.MaxWait)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"concurrency/fetchertest"
+	"concurrency/ratelimit"
+)
+
+func TestClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   string
+		level int
+		want  int
+	}{
+		{name: "seed", url: "http://golang.org/", level: 0, want: Seed},
+		{name: "sitemap", url: "http://golang.org/sitemap.xml", level: 3, want: Sitemap},
+		{name: "shallow", url: "http://golang.org/pkg/", level: 1, want: Shallow},
+		{name: "deep", url: "http://golang.org/pkg/fmt/", level: 2, want: Deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := class(tt.url, tt.level); got != tt.want {
+				t.Errorf("class() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlLanes(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := ratelimit.NewFakeClock(start)
+	rec := &fetchertest.RecordingFetcher{Fetcher: fetcher, Clock: clock}
+	lanes := NewClassLanes(ratelimit.NewInterval(time.Second, clock), clock)
+
+	clock.Drive(func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		CrawlLanes(rec, "http://golang.org/", 4, &wg, lanes)
+		wg.Wait()
+	})
+
+	fetches := rec.Fetches()
+	fetchertest.AssertMinSpacing(t, fetches, start, time.Second, 1)
+	if got := rec.URLs()[0]; got != "http://golang.org/" {
+		t.Errorf("first fetch got = %s, want the seed", got)
+	}
+
+	stats := lanes.Stats()
+	events := 0
+	for _, s := range stats {
+		events += s.Events
+	}
+	if events != len(fetches) {
+		t.Errorf("Stats() got %d events, want %d", events, len(fetches))
+	}
+	if stats[Seed].Events != 1 || stats[Shallow].Events != 2 {
+		t.Errorf("Stats() got %d seed and %d shallow events, want 1 and 2", stats[Seed].Events, stats[Shallow].Events)
+	}
+
+	var b strings.Builder
+	PrintLaneStats(&b, lanes)
+	if !strings.HasPrefix(b.String(), "seed: 1 fetches") {
+		t.Errorf("PrintLaneStats() got = %q", b.String())
+	}
+}
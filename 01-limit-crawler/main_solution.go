@@ -10,21 +10,36 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"sync"
 	"time"
+
+	"concurrency/ratelimit"
 )
 
+// clock is the clock main paces the crawl with. check_test.go swaps
+// in a ratelimit.FakeClock so the test does not take a second per page.
+var clock = ratelimit.SystemClock
+
 // Crawl uses `fetcher` from the `mockfetcher.go` file to imitate a
-// real crawler. It crawls until the maximum depth has reached.
-func Crawl(url string, depth int, wg *sync.WaitGroup, ticker *time.Ticker) {
+// real crawler. It crawls until the maximum depth has reached. Every
+// fetch waits for limiter first, so any Limiter paces the crawl: a
+// fixed interval, a token bucket, or one shared with other crawls.
+func Crawl(url string, depth int, wg *sync.WaitGroup, limiter ratelimit.Limiter) {
 	defer wg.Done()
 
 	if depth <= 0 {
 		return
 	}
 
+	if err := limiter.Wait(context.Background()); err != nil {
+		fmt.Println(err)
+		return
+	}
 	body, urls, err := fetcher.Fetch(url)
 	if err != nil {
 		fmt.Println(err)
@@ -37,18 +52,28 @@ func Crawl(url string, depth int, wg *sync.WaitGroup, ticker *time.Ticker) {
 	for _, u := range urls {
 		// Do not remove the `go` keyword, as Crawl() must be
 		// called concurrently
-		<-ticker.C
 		log.Println(u, depth)
-		go Crawl(u, depth-1, wg, ticker)
+		go Crawl(u, depth-1, wg, limiter)
 	}
 }
 
 func main() {
 	var wg sync.WaitGroup
 
+	// Crawls sharing a token server, given as CRAWL_LIMITER=network:addr,
+	// share its rate; on their own they allow one fetch per second.
+	var limiter ratelimit.Limiter = ratelimit.NewInterval(time.Second, clock)
+	if addr := os.Getenv("CRAWL_LIMITER"); addr != "" {
+		network, addr, _ := strings.Cut(addr, ":")
+		client, err := ratelimit.Dial(network, addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer client.Close()
+		limiter = client
+	}
+
 	wg.Add(1)
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
-	Crawl("http://golang.org/", 4, &wg, ticker)
+	Crawl("http://golang.org/", 4, &wg, limiter)
 	wg.Wait()
 }
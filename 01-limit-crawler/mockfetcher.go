@@ -19,15 +19,15 @@ type mockResult struct {
 
 // Fetch pretends to retrieve the URLs and its subpages
 func (f MockFetcher) Fetch(url string) (string, []string, error) {
-	fetchSignalInstance() <- true
 	if res, ok := f[url]; ok {
 		return res.body, res.urls, nil
 	}
 	return "", nil, fmt.Errorf("not found: %s", url)
 }
 
-// fetcher is a populated MockFetcher.
-var fetcher = MockFetcher{
+// fetcher is a populated MockFetcher. Tests wrap it
+// in a fetchertest.RecordingFetcher.
+var fetcher Fetcher = MockFetcher{
 	"http://golang.org/": &mockResult{
 		"The Go Programming Language",
 		[]string{
@@ -59,19 +59,3 @@ var fetcher = MockFetcher{
 		},
 	},
 }
-
-//////////////////////////////////////////////////////////////////////
-// Code below is mainly used to test whether a solution is correct or
-// not
-
-// fetchSignal is used to test whether the solution is correct
-var fetchSignal chan bool
-
-// fetchSignalInstance is a singleton to access fetchSignal
-func fetchSignalInstance() chan bool {
-	if fetchSignal == nil {
-		// Use buffered channel to avoid blocking
-		fetchSignal = make(chan bool, 1000)
-	}
-	return fetchSignal
-}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+// Fetcher is implemented by MockFetcher.
+type Fetcher interface {
+	Fetch(url string) (body string, urls []string, err error)
+}
+
+// CrawlPerHost is Crawl with a budget per host instead of one global
+// ticker: limiter is acquired for the host of every URL before f
+// fetches it and released once the fetch is done. A ratelimit.Observer,
+// such as ratelimit.Adaptive, is told how long each fetch took and
+// how it failed, if it did; it decides which errors are a sign of
+// load, so a page that is not found does not slow the host down.
+func CrawlPerHost(f Fetcher, url string, depth int, wg *sync.WaitGroup, limiter ratelimit.HostLimiter) {
+	defer wg.Done()
+
+	if depth <= 0 {
+		return
+	}
+
+	release, err := limiter.Acquire(context.Background(), ratelimit.Host(url))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	start := time.Now()
+	body, urls, err := f.Fetch(url)
+	release()
+	if o, ok := limiter.(ratelimit.Observer); ok {
+		o.Observe(ratelimit.Host(url), ratelimit.Response{Latency: time.Since(start), Err: err})
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("found: %s %q\n", url, body)
+
+	wg.Add(len(urls))
+	for _, u := range urls {
+		go CrawlPerHost(f, u, depth-1, wg, limiter)
+	}
+}
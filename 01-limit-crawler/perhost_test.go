@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"concurrency/fetchertest"
+	"concurrency/ratelimit"
+)
+
+func TestCrawlPerHost(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := ratelimit.NewFakeClock(start)
+	rec := &fetchertest.RecordingFetcher{Fetcher: fetcher, Clock: clock}
+
+	clock.Drive(func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		CrawlPerHost(rec, "http://golang.org/", 4, &wg, ratelimit.NewPerHost(time.Second, 1, 1, clock))
+		wg.Wait()
+	})
+
+	fetches := rec.Fetches()
+	if len(fetches) == 0 {
+		t.Fatal("no fetches recorded")
+	}
+	fetchertest.AssertMinSpacing(t, fetches, start, time.Second, 1)
+	fetchertest.AssertMaxParallelism(t, fetches, 1)
+}
+
+func TestCrawlPerHostAdaptive(t *testing.T) {
+	clock := ratelimit.NewFakeClock(time.Unix(0, 0))
+	limiter := ratelimit.NewAdaptive(1, 100, clock)
+
+	clock.Drive(func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		CrawlPerHost(fetcher, "http://golang.org/", 4, &wg, limiter)
+		wg.Wait()
+	})
+
+	// http://golang.org/cmd/ is not found, which says
+	// nothing about the server's load.
+	for _, rc := range limiter.History()["golang.org"] {
+		if rc.Reason != "start" && rc.Reason != "ok" {
+			t.Errorf("History() has a %q change, want only good responses", rc.Reason)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/tour/tree"
+)
+
+const deepSize = 1_000_000
+
+// leftChain returns a tree holding 1, 2, ..., n where
+// every node only has a left child.
+func leftChain(n int) *tree.Tree {
+	var t *tree.Tree
+	for v := 1; v <= n; v++ {
+		t = &tree.Tree{Left: t, Value: v}
+	}
+	return t
+}
+
+// rightChain returns a tree holding 1, 2, ..., n where
+// every node only has a right child.
+func rightChain(n int) *tree.Tree {
+	var t *tree.Tree
+	for v := n; v >= 1; v-- {
+		t = &tree.Tree{Value: v, Right: t}
+	}
+	return t
+}
+
+// recursiveWalk is the recursive walk that goWalk replaced,
+// kept as a benchmark baseline.
+func recursiveWalk(t *tree.Tree, ch chan int) {
+	if t == nil {
+		return
+	}
+	recursiveWalk(t.Left, ch)
+	ch <- t.Value
+	recursiveWalk(t.Right, ch)
+}
+
+func TestWalkDeep(t *testing.T) {
+	tests := []struct {
+		name string
+		tree *tree.Tree
+	}{
+		{"left chain", leftChain(deepSize)},
+		{"right chain", rightChain(deepSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan int, 1024)
+			go Walk(tt.tree, ch)
+			want := 1
+			for v := range ch {
+				if v != want {
+					t.Fatalf("Walk() got %v at position %v, want %v", v, want-1, want)
+				}
+				want++
+			}
+			if want-1 != deepSize {
+				t.Errorf("Walk() got %v values, want %v", want-1, deepSize)
+			}
+		})
+	}
+}
+
+func BenchmarkWalkDeep(b *testing.B) {
+	trees := []struct {
+		name string
+		tree *tree.Tree
+	}{
+		{"left chain", leftChain(deepSize)},
+		{"random", newTree(deepSize)},
+	}
+	walks := []struct {
+		name string
+		walk func(*tree.Tree, chan int)
+	}{
+		{"iterative", Walk},
+		{"recursive", func(t *tree.Tree, ch chan int) {
+			defer close(ch)
+			recursiveWalk(t, ch)
+		}},
+	}
+
+	for _, tr := range trees {
+		for _, w := range walks {
+			b.Run(fmt.Sprintf("%s/%s", tr.name, w.name), func(b *testing.B) {
+				for b.Loop() {
+					ch := make(chan int, 1024)
+					go w.walk(tr.tree, ch)
+					for range ch {
+					}
+				}
+			})
+		}
+	}
+}
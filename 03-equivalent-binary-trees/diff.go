@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/tour/tree"
+)
+
+// Side tells which of the two compared trees a value was found in.
+type Side int
+
+const (
+	OnlyT1 Side = iota // value is only in the first tree
+	OnlyT2             // value is only in the second tree
+	InBoth             // value is in both trees
+)
+
+func (s Side) String() string {
+	switch s {
+	case OnlyT1:
+		return "only t1"
+	case OnlyT2:
+		return "only t2"
+	case InBoth:
+		return "both"
+	}
+	return fmt.Sprintf("Side(%d)", int(s))
+}
+
+// Delta reports that Value occurs Count times on Side.
+// A value held three times in t1 and once in t2 gives
+// one InBoth delta with Count 1 and one OnlyT1 delta with Count 2.
+type Delta struct {
+	Value int
+	Side  Side
+	Count int
+}
+
+// Diff returns the differences between t1 and t2 in value order,
+// see DiffStream. max <= 0 means no limit.
+func Diff(t1, t2 *tree.Tree, max int) []Delta {
+	var res []Delta
+	for d := range DiffStream(t1, t2, max, nil) {
+		res = append(res, d)
+	}
+	return res
+}
+
+// DiffStream walks t1 and t2 concurrently, merges the two in-order
+// walks and sends a Delta for every distinct value on the returned
+// channel. The channel is closed once both walks are done, once max
+// OnlyT1/OnlyT2 deltas have been sent (max <= 0 means no limit) or
+// once done is closed.
+func DiffStream(t1, t2 *tree.Tree, max int, done <-chan struct{}) <-chan Delta {
+	out := make(chan Delta)
+	go func() {
+		defer close(out)
+		stop := make(chan struct{})
+		defer close(stop)
+
+		ch1, ch2 := make(chan int), make(chan int)
+		go walk(t1, ch1, stop)
+		go walk(t2, ch2, stop)
+
+		diffs := 0
+		emit := func(v int, s Side, n int) bool {
+			if n == 0 {
+				return true
+			}
+			select {
+			case out <- Delta{Value: v, Side: s, Count: n}:
+			case <-done:
+				return false
+			}
+			if s != InBoth {
+				diffs++
+			}
+			return max <= 0 || diffs < max
+		}
+
+		r1, r2 := newRuns(ch1), newRuns(ch2)
+		v1, n1, ok1 := r1.next()
+		v2, n2, ok2 := r2.next()
+		for ok1 || ok2 {
+			switch {
+			case !ok2 || ok1 && v1 < v2:
+				if !emit(v1, OnlyT1, n1) {
+					return
+				}
+				v1, n1, ok1 = r1.next()
+			case !ok1 || v2 < v1:
+				if !emit(v2, OnlyT2, n2) {
+					return
+				}
+				v2, n2, ok2 = r2.next()
+			default:
+				if !emit(v1, InBoth, min(n1, n2)) ||
+					!emit(v1, OnlyT1, n1-min(n1, n2)) ||
+					!emit(v2, OnlyT2, n2-min(n1, n2)) {
+					return
+				}
+				v1, n1, ok1 = r1.next()
+				v2, n2, ok2 = r2.next()
+			}
+		}
+	}()
+	return out
+}
+
+// runs groups equal consecutive values read from a channel.
+type runs struct {
+	ch   <-chan int
+	head int
+	ok   bool
+}
+
+func newRuns(ch <-chan int) *runs {
+	r := &runs{ch: ch}
+	r.head, r.ok = <-ch
+	return r
+}
+
+// next returns the next value and how many times in a row it occurs.
+func (r *runs) next() (v, n int, ok bool) {
+	if !r.ok {
+		return 0, 0, false
+	}
+	v = r.head
+	for r.ok && r.head == v {
+		n++
+		r.head, r.ok = <-r.ch
+	}
+	return v, n, true
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tour/tree"
+)
+
+// build returns a binary search tree holding values,
+// inserted in the given order.
+func build(values ...int) *tree.Tree {
+	var t *tree.Tree
+	for _, v := range values {
+		t = insert(t, v)
+	}
+	return t
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		t1   *tree.Tree
+		t2   *tree.Tree
+		max  int
+		want []Delta
+	}{
+		{
+			name: "identical trees",
+			t1:   build(2, 1, 3),
+			t2:   build(1, 2, 3),
+			want: []Delta{{1, InBoth, 1}, {2, InBoth, 1}, {3, InBoth, 1}},
+		},
+		{
+			name: "disjoint values",
+			t1:   build(1, 3),
+			t2:   build(2, 4),
+			want: []Delta{{1, OnlyT1, 1}, {2, OnlyT2, 1}, {3, OnlyT1, 1}, {4, OnlyT2, 1}},
+		},
+		{
+			name: "duplicates counted",
+			t1:   build(5, 5, 5, 7),
+			t2:   build(5, 7, 7),
+			want: []Delta{{5, InBoth, 1}, {5, OnlyT1, 2}, {7, InBoth, 1}, {7, OnlyT2, 1}},
+		},
+		{
+			name: "nil first tree",
+			t1:   nil,
+			t2:   build(1, 2),
+			want: []Delta{{1, OnlyT2, 1}, {2, OnlyT2, 1}},
+		},
+		{
+			name: "both nil trees",
+			t1:   nil,
+			t2:   nil,
+			want: nil,
+		},
+		{
+			name: "stops at max differences",
+			t1:   tree.New(1),
+			t2:   tree.New(2),
+			max:  2,
+			want: []Delta{{1, OnlyT1, 1}, {2, InBoth, 1}, {3, OnlyT1, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.t1, tt.t2, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffStreamDone(t *testing.T) {
+	done := make(chan struct{})
+	ch := DiffStream(newTree(1000), build(), 0, done)
+	if d := <-ch; d != (Delta{1, OnlyT1, 1}) {
+		t.Errorf("DiffStream() first = %v, want %v", d, Delta{1, OnlyT1, 1})
+	}
+	close(done)
+	for range ch {
+	}
+}
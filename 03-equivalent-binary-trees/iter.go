@@ -0,0 +1,57 @@
+package main
+
+import (
+	"iter"
+
+	"golang.org/x/tour/tree"
+)
+
+// All returns an iterator over the values of t in order.
+// Unlike Walk it runs on the caller's goroutine, so there is
+// no channel hand-off per value.
+func All(t *tree.Tree) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		forward(t, yield)
+	}
+}
+
+// Backward returns an iterator over the values of t in reverse order.
+func Backward(t *tree.Tree) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		backward(t, yield)
+	}
+}
+
+func forward(t *tree.Tree, yield func(int) bool) bool {
+	if t == nil {
+		return true
+	}
+	return forward(t.Left, yield) && yield(t.Value) && forward(t.Right, yield)
+}
+
+func backward(t *tree.Tree, yield func(int) bool) bool {
+	if t == nil {
+		return true
+	}
+	return backward(t.Right, yield) && yield(t.Value) && backward(t.Left, yield)
+}
+
+// SameIter determines whether the trees t1 and t2 contain the same
+// values, like Same, but pulls from two iterators instead of two
+// goroutines.
+func SameIter(t1, t2 *tree.Tree) bool {
+	next1, stop1 := iter.Pull(All(t1))
+	defer stop1()
+	next2, stop2 := iter.Pull(All(t2))
+	defer stop2()
+	for {
+		v1, ok1 := next1()
+		v2, ok2 := next2()
+		if ok1 != ok2 || v1 != v2 {
+			return false
+		}
+		if !ok1 {
+			return true
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/tour/tree"
+)
+
+// newTree returns a randomly-structured binary search tree
+// holding the values 1, 2, ..., n.
+func newTree(n int) *tree.Tree {
+	var t *tree.Tree
+	for _, v := range rand.Perm(n) {
+		t = insert(t, v+1)
+	}
+	return t
+}
+
+func insert(t *tree.Tree, v int) *tree.Tree {
+	if t == nil {
+		return &tree.Tree{Value: v}
+	}
+	if v < t.Value {
+		t.Left = insert(t.Left, v)
+	} else {
+		t.Right = insert(t.Right, v)
+	}
+	return t
+}
+
+func TestAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		tree     *tree.Tree
+		expected []int
+	}{
+		{
+			name:     "tree 1",
+			tree:     tree.New(1),
+			expected: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		},
+		{
+			name:     "tree 3",
+			tree:     tree.New(3),
+			expected: []int{3, 6, 9, 12, 15, 18, 21, 24, 27, 30},
+		},
+		{
+			name:     "nil tree",
+			tree:     nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slices.Collect(All(tt.tree)); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("All() got = %v, want %v", got, tt.expected)
+			}
+			want := slices.Clone(tt.expected)
+			slices.Reverse(want)
+			if got := slices.Collect(Backward(tt.tree)); !reflect.DeepEqual(got, want) {
+				t.Errorf("Backward() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAllBreak(t *testing.T) {
+	var got []int
+	for v := range All(tree.New(1)) {
+		if v > 3 {
+			break
+		}
+		got = append(got, v)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("All() with break got = %v, want %v", got, want)
+	}
+}
+
+func TestSameIter(t *testing.T) {
+	tests := []struct {
+		name string
+		t1   *tree.Tree
+		t2   *tree.Tree
+		want bool
+	}{
+		{"identical trees", tree.New(1), tree.New(1), true},
+		{"different trees", tree.New(1), tree.New(2), false},
+		{"nil first tree", nil, tree.New(1), false},
+		{"nil second tree", tree.New(1), nil, false},
+		{"both nil trees", nil, nil, true},
+		{"prefix", newTree(9), newTree(10), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameIter(tt.t1, tt.t2); got != tt.want {
+				t.Errorf("SameIter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const benchSize = 100_000
+
+func BenchmarkWalk(b *testing.B) {
+	t := newTree(benchSize)
+	for b.Loop() {
+		ch := make(chan int)
+		go Walk(t, ch)
+		for range ch {
+		}
+	}
+}
+
+func BenchmarkAll(b *testing.B) {
+	t := newTree(benchSize)
+	for b.Loop() {
+		for range All(t) {
+		}
+	}
+}
+
+func BenchmarkSame(b *testing.B) {
+	t1, t2 := newTree(benchSize), newTree(benchSize)
+	for b.Loop() {
+		Same(t1, t2)
+	}
+}
+
+func BenchmarkSameIter(b *testing.B) {
+	t1, t2 := newTree(benchSize), newTree(benchSize)
+	for b.Loop() {
+		SameIter(t1, t2)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync"
+
+	"golang.org/x/tour/tree"
+)
+
+// segmentBuffer lets subtree walkers run ahead of the segment
+// being sent.
+const segmentBuffer = 256
+
+// ParallelWalk walks the tree t like Walk, sending all values to ch in
+// order and closing it. The nodes above depth are visited directly and
+// every subtree rooted at depth is walked on its own goroutine into a
+// buffered segment; the segments are sent one after the other, in the
+// order of the walk. t need not be a search tree. depth <= 0 is a
+// plain Walk.
+func ParallelWalk(t *tree.Tree, ch chan int, depth int) {
+	defer close(ch)
+	var segs []chan int
+	split(t, depth, func(sub *tree.Tree) {
+		seg := make(chan int, segmentBuffer)
+		go walk(sub, seg, nil)
+		segs = append(segs, seg)
+	}, func(v int) {
+		seg := make(chan int, 1)
+		seg <- v
+		close(seg)
+		segs = append(segs, seg)
+	})
+
+	// Every segment is a contiguous run of the in-order walk,
+	// so draining them one after the other keeps the order.
+	for _, seg := range segs {
+		for v := range seg {
+			ch <- v
+		}
+	}
+}
+
+// ParallelWalkUnordered sends all values of t to ch in no particular
+// order and closes it. It splits the work like ParallelWalk but sends
+// values as they come, which suits aggregations such as a sum or a count.
+func ParallelWalkUnordered(t *tree.Tree, ch chan int, depth int) {
+	defer close(ch)
+	var wg sync.WaitGroup
+	split(t, depth, func(sub *tree.Tree) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			goWalk(sub, ch, nil)
+		}()
+	}, func(v int) {
+		ch <- v
+	})
+	wg.Wait()
+}
+
+// split visits the nodes of t above depth in order, calling value for
+// each of them and subtree for every non-nil subtree rooted at depth.
+func split(t *tree.Tree, depth int, subtree func(*tree.Tree), value func(int)) {
+	if t == nil {
+		return
+	}
+	if depth <= 0 {
+		subtree(t)
+		return
+	}
+	split(t.Left, depth-1, subtree, value)
+	value(t.Value)
+	split(t.Right, depth-1, subtree, value)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/tour/tree"
+)
+
+func collect(walk func(*tree.Tree, chan int), t *tree.Tree) []int {
+	ch := make(chan int)
+	go walk(t, ch)
+	var res []int
+	for v := range ch {
+		res = append(res, v)
+	}
+	return res
+}
+
+func TestParallelWalk(t *testing.T) {
+	tests := []struct {
+		name  string
+		tree  *tree.Tree
+		depth int
+	}{
+		{"nil tree", nil, 3},
+		{"tree 1 depth 0", tree.New(1), 0},
+		{"tree 1 depth 2", tree.New(1), 2},
+		{"tree 1 deeper than tree", tree.New(1), 20},
+		{"duplicates", build(4, 2, 4, 6, 2, 4, 8), 2},
+		{"large tree", newTree(10_000), 4},
+		{"not a search tree", &tree.Tree{
+			Left:  &tree.Tree{Left: &tree.Tree{Value: 9}, Value: 3, Right: &tree.Tree{Value: 1}},
+			Value: 7,
+			Right: &tree.Tree{Left: &tree.Tree{Value: 2}, Value: 8, Right: &tree.Tree{Value: 0}},
+		}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := slices.Collect(All(tt.tree))
+
+			got := collect(func(t *tree.Tree, ch chan int) { ParallelWalk(t, ch, tt.depth) }, tt.tree)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParallelWalk() got = %v, want %v", got, want)
+			}
+
+			got = collect(func(t *tree.Tree, ch chan int) { ParallelWalkUnordered(t, ch, tt.depth) }, tt.tree)
+			slices.Sort(got)
+			if want := slices.Sorted(slices.Values(want)); !reflect.DeepEqual(got, want) {
+				t.Errorf("ParallelWalkUnordered() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkParallelWalk(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000, 1_000_000} {
+		t := newTree(n)
+		b.Run(fmt.Sprintf("n=%d/Walk", n), func(b *testing.B) {
+			for b.Loop() {
+				collect(Walk, t)
+			}
+		})
+		for _, depth := range []int{2, 4, 6} {
+			b.Run(fmt.Sprintf("n=%d/ordered/depth=%d", n, depth), func(b *testing.B) {
+				for b.Loop() {
+					collect(func(t *tree.Tree, ch chan int) { ParallelWalk(t, ch, depth) }, t)
+				}
+			})
+			b.Run(fmt.Sprintf("n=%d/unordered/depth=%d", n, depth), func(b *testing.B) {
+				for b.Loop() {
+					collect(func(t *tree.Tree, ch chan int) { ParallelWalkUnordered(t, ch, depth) }, t)
+				}
+			})
+		}
+	}
+}
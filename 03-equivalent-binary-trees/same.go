@@ -0,0 +1,64 @@
+package main
+
+import (
+	"maps"
+
+	"golang.org/x/tour/tree"
+)
+
+// SameStructure determines whether the trees t1 and t2 have
+// the same shape and hold the same value at every node.
+func SameStructure(t1, t2 *tree.Tree) bool {
+	if t1 == nil || t2 == nil {
+		return t1 == t2
+	}
+	return t1.Value == t2.Value &&
+		SameStructure(t1.Left, t2.Left) &&
+		SameStructure(t1.Right, t2.Right)
+}
+
+// SameSet determines whether the trees t1 and t2 contain the same
+// values, ignoring how many times each value occurs. Unlike Same it
+// does not rely on the trees being sorted.
+func SameSet(t1, t2 *tree.Tree) bool {
+	c1, c2 := countBoth(t1, t2)
+	if len(c1) != len(c2) {
+		return false
+	}
+	for v := range c1 {
+		if _, ok := c2[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SameMultiset determines whether the trees t1 and t2 contain the
+// same values the same number of times. Unlike Same it does not rely
+// on the trees being sorted.
+func SameMultiset(t1, t2 *tree.Tree) bool {
+	c1, c2 := countBoth(t1, t2)
+	return maps.Equal(c1, c2)
+}
+
+// countBoth walks t1 and t2 concurrently and counts their values.
+func countBoth(t1, t2 *tree.Tree) (c1, c2 map[int]int) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c1 = count(t1)
+	}()
+	c2 = count(t2)
+	<-done
+	return c1, c2
+}
+
+func count(t *tree.Tree) map[int]int {
+	ch := make(chan int)
+	go Walk(t, ch)
+	m := make(map[int]int)
+	for v := range ch {
+		m[v]++
+	}
+	return m
+}
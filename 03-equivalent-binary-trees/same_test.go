@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/tour/tree"
+)
+
+func TestSameModes(t *testing.T) {
+	// unsorted holds 1, 2 and 3 but is not a search tree.
+	unsorted := &tree.Tree{
+		Left:  &tree.Tree{Value: 3},
+		Value: 1,
+		Right: &tree.Tree{Value: 2},
+	}
+	random := tree.New(1)
+
+	tests := []struct {
+		name      string
+		t1        *tree.Tree
+		t2        *tree.Tree
+		structure bool
+		set       bool
+		multiset  bool
+	}{
+		{
+			name:      "identical shape",
+			t1:        build(2, 1, 3),
+			t2:        build(2, 1, 3),
+			structure: true,
+			set:       true,
+			multiset:  true,
+		},
+		{
+			name:     "right chain and balanced",
+			t1:       build(1, 2, 3),
+			t2:       build(2, 1, 3),
+			set:      true,
+			multiset: true,
+		},
+		{
+			name:     "left chain and right chain",
+			t1:       build(3, 2, 1),
+			t2:       build(1, 2, 3),
+			set:      true,
+			multiset: true,
+		},
+		{
+			name: "duplicates",
+			t1:   build(2, 1, 3, 3),
+			t2:   build(2, 1, 3),
+			set:  true,
+		},
+		{
+			name: "different values",
+			t1:   build(2, 1, 3),
+			t2:   build(2, 1, 4),
+		},
+		{
+			name:     "unsorted tree",
+			t1:       unsorted,
+			t2:       build(2, 1, 3),
+			set:      true,
+			multiset: true,
+		},
+		{
+			name:      "random tree with itself",
+			t1:        random,
+			t2:        random,
+			structure: true,
+			set:       true,
+			multiset:  true,
+		},
+		{
+			name: "random trees different values",
+			t1:   tree.New(1),
+			t2:   tree.New(2),
+		},
+		{
+			name:      "both nil trees",
+			structure: true,
+			set:       true,
+			multiset:  true,
+		},
+		{
+			name: "nil second tree",
+			t1:   tree.New(1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameStructure(tt.t1, tt.t2); got != tt.structure {
+				t.Errorf("SameStructure() = %v, want %v", got, tt.structure)
+			}
+			if got := SameSet(tt.t1, tt.t2); got != tt.set {
+				t.Errorf("SameSet() = %v, want %v", got, tt.set)
+			}
+			if got := SameMultiset(tt.t1, tt.t2); got != tt.multiset {
+				t.Errorf("SameMultiset() = %v, want %v", got, tt.multiset)
+			}
+		})
+	}
+}
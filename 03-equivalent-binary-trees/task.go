@@ -7,20 +7,35 @@ import (
 // Walk walks the tree t sending all values
 // from the tree to the channel ch.
 func Walk(t *tree.Tree, ch chan int) {
+	walk(t, ch, nil)
+}
+
+// walk is Walk that gives up once done is closed.
+func walk(t *tree.Tree, ch chan int, done <-chan struct{}) {
 	defer close(ch)
-	goWalk(t, ch)
+	goWalk(t, ch, done)
 }
-func goWalk(t *tree.Tree, ch chan int) {
-	if t == nil {
-		return
-	}
-	if t.Left != nil {
-		goWalk(t.Left, ch)
-	}
-	ch <- t.Value
-	if t.Right != nil {
-		goWalk(t.Right, ch)
+
+// goWalk reports false if it stopped early because done was closed.
+// A nil done never closes. It keeps the path to the current node on
+// an explicit stack instead of recursing, so a degenerate tree does
+// not grow the goroutine stack with its height.
+func goWalk(t *tree.Tree, ch chan int, done <-chan struct{}) bool {
+	var stack []*tree.Tree
+	for t != nil || len(stack) > 0 {
+		for ; t != nil; t = t.Left {
+			stack = append(stack, t)
+		}
+		t = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		select {
+		case ch <- t.Value:
+		case <-done:
+			return false
+		}
+		t = t.Right
 	}
+	return true
 }
 
 // Same determines whether the trees
@@ -113,11 +113,16 @@ func TestSame(t *testing.T) {
 		},
 		{
 			name: "same values different structure",
-
-			t1:   tree.New(1),
-			t2:   tree.New(1),
+			t1:   build(1, 2, 3),
+			t2:   build(2, 1, 3),
 			want: true,
 		},
+		{
+			name: "same values different counts",
+			t1:   build(2, 1, 3),
+			t2:   build(2, 1, 3, 3),
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
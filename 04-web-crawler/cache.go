@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ErrNotModified is returned by a ConditionalFetcher
+// when the page has not changed.
+var ErrNotModified = errors.New("not modified")
+
+// Validators identify a version of a page, as sent by
+// a server in its ETag and Last-Modified headers.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ConditionalFetcher is a fetcher that can skip pages which have not
+// changed. HTTPFetcher is one.
+type ConditionalFetcher interface {
+	// FetchIfChanged fetches url unless the version identified by v
+	// is still current, in which case it returns ErrNotModified.
+	// It also returns the validators of the version it fetched.
+	FetchIfChanged(ctx context.Context, url string, v Validators) (body string, urls []string, nv Validators, err error)
+}
+
+// FetchCache holds the pages of one crawl, to be
+// compared with and served to the next one.
+type FetchCache struct {
+	Pages map[string]*CachedPage `json:"pages"`
+}
+
+// CachedPage is a page as it was last fetched.
+type CachedPage struct {
+	Body  string   `json:"body"`
+	Links []string `json:"links,omitempty"`
+	Validators
+}
+
+// LoadFetchCache reads a cache written by Save.
+// A missing file is an empty cache.
+func LoadFetchCache(path string) (*FetchCache, error) {
+	fc := &FetchCache{Pages: make(map[string]*CachedPage)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, fc); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// Save writes the cache to path. Like a checkpoint, the file is
+// replaced atomically.
+func (fc *FetchCache) Save(path string) error {
+	return writeFileAtomic(path, fc)
+}
+
+// ChangeReport sorts the pages of a crawl by how they compare with the
+// previous crawl. Each list is sorted.
+type ChangeReport struct {
+	New       []string `json:"new,omitempty"`
+	Changed   []string `json:"changed,omitempty"`
+	Unchanged []string `json:"unchanged,omitempty"`
+	// Removed pages were in the previous crawl but are gone: not
+	// linked any more, or answered 404 Not Found or 410 Gone.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// CachingFetcher is a Fetcher that remembers the pages it fetches and
+// compares them with those of a previous crawl. If the fetcher it
+// wraps is a ConditionalFetcher, pages in the previous crawl are
+// fetched with its validators and, if unchanged, served from the
+// cache; otherwise a page is unchanged if its body is.
+//
+// Use one CachingFetcher per crawl, then save its Cache for the next.
+type CachingFetcher struct {
+	fetcher Fetcher
+	prev    *FetchCache
+
+	mu     sync.Mutex
+	next   *FetchCache
+	report ChangeReport
+}
+
+// NewCachingFetcher wraps f. prev is the cache of the previous crawl,
+// or nil for the first one.
+func NewCachingFetcher(f Fetcher, prev *FetchCache) *CachingFetcher {
+	if prev == nil {
+		prev = &FetchCache{}
+	}
+	return &CachingFetcher{
+		fetcher: f,
+		prev:    prev,
+		next:    &FetchCache{Pages: make(map[string]*CachedPage)},
+	}
+}
+
+func (f *CachingFetcher) Fetch(url string) (string, []string, error) {
+	return f.FetchContext(context.Background(), url)
+}
+
+func (f *CachingFetcher) FetchContext(ctx context.Context, url string) (string, []string, error) {
+	old := f.prev.Pages[url]
+	var (
+		body string
+		urls []string
+		v    Validators
+		err  error
+	)
+	if cf, ok := f.fetcher.(ConditionalFetcher); ok {
+		if old != nil {
+			v = old.Validators
+		}
+		body, urls, v, err = cf.FetchIfChanged(ctx, url, v)
+		if errors.Is(err, ErrNotModified) && old != nil {
+			body, urls, err = old.Body, old.Links, nil
+		}
+	} else {
+		body, urls, err = WithContext(f.fetcher).Fetch(ctx, url)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case err != nil:
+		// A page that is gone is dropped; after any other error the
+		// old version is kept, it may well still be there.
+		var he *HTTPError
+		if old != nil && !(errors.As(err, &he) && (he.StatusCode == http.StatusNotFound || he.StatusCode == http.StatusGone)) {
+			f.next.Pages[url] = old
+		}
+		return "", nil, err
+	case old == nil:
+		f.report.New = append(f.report.New, url)
+	case body == old.Body && slices.Equal(urls, old.Links):
+		f.report.Unchanged = append(f.report.Unchanged, url)
+	default:
+		f.report.Changed = append(f.report.Changed, url)
+	}
+	f.next.Pages[url] = &CachedPage{Body: body, Links: urls, Validators: v}
+	return body, urls, nil
+}
+
+// Cache returns the pages fetched so far, to be saved for the next
+// crawl. Pages of the previous crawl that failed with an error other
+// than Not Found or Gone are carried over.
+func (f *CachingFetcher) Cache() *FetchCache {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &FetchCache{Pages: maps.Clone(f.next.Pages)}
+}
+
+// Report compares the pages fetched so far with the previous crawl.
+// Call it once the crawl is over, or pages yet to be fetched show up
+// as removed.
+func (f *CachingFetcher) Report() ChangeReport {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := ChangeReport{
+		New:       slices.Sorted(slices.Values(f.report.New)),
+		Changed:   slices.Sorted(slices.Values(f.report.Changed)),
+		Unchanged: slices.Sorted(slices.Values(f.report.Unchanged)),
+	}
+	for u := range f.prev.Pages {
+		if f.next.Pages[u] == nil {
+			r.Removed = append(r.Removed, u)
+		}
+	}
+	slices.Sort(r.Removed)
+	return r
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// versionedSite serves pages that can be edited between crawls.
+// Every page has an ETag, and a request with a matching If-None-Match
+// gets 304 Not Modified.
+type versionedSite struct {
+	mu          sync.Mutex
+	pages       map[string]string
+	notModified int
+}
+
+func (s *versionedSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, ok := s.pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h := fnv.New64a()
+	h.Write([]byte(body))
+	etag := fmt.Sprintf(`"%x"`, h.Sum64())
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(body))
+}
+
+func (s *versionedSite) set(path, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if body == "" {
+		delete(s.pages, path)
+	} else {
+		s.pages[path] = body
+	}
+}
+
+func TestCachingFetcher(t *testing.T) {
+	site := &versionedSite{pages: map[string]string{
+		"/":  `<a href="/a">A</a> <a href="/b">B</a> <a href="/c">C</a>`,
+		"/a": "A",
+		"/b": "B",
+		"/c": "C",
+	}}
+	ts := httptest.NewServer(site)
+	defer ts.Close()
+	path := filepath.Join(t.TempDir(), "cache.json")
+	u := func(paths ...string) []string {
+		var us []string
+		for _, p := range paths {
+			us = append(us, ts.URL+p)
+		}
+		return us
+	}
+
+	crawl := func() (ChangeReport, []string) {
+		t.Helper()
+		prev, err := LoadFetchCache(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := NewCachingFetcher(&HTTPFetcher{}, prev)
+		res, err := NewCrawler(WithContext(f)).Run(context.Background(), ts.URL+"/", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Cache().Save(path); err != nil {
+			t.Fatal(err)
+		}
+		var bodies []string
+		for _, p := range res.Pages {
+			if p.URL == ts.URL+"/b" {
+				bodies = append(bodies, p.Body)
+			}
+		}
+		return f.Report(), bodies
+	}
+
+	got, _ := crawl()
+	want := ChangeReport{New: u("/", "/a", "/b", "/c")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("first crawl Report() = %+v, want %+v", got, want)
+	}
+
+	site.set("/", `<a href="/a">A</a> <a href="/b">B</a> <a href="/d">D</a>`)
+	site.set("/a", "A, edited")
+	site.set("/c", "")
+	site.set("/d", "D")
+	got, bodies := crawl()
+	want = ChangeReport{
+		New:       u("/d"),
+		Changed:   u("/", "/a"),
+		Unchanged: u("/b"),
+		Removed:   u("/c"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("second crawl Report() = %+v, want %+v", got, want)
+	}
+	if site.notModified != 1 {
+		t.Errorf("second crawl got %d Not Modified responses, want 1", site.notModified)
+	}
+	if !reflect.DeepEqual(bodies, []string{"B"}) {
+		t.Errorf("unchanged page served with bodies %q, want %q", bodies, []string{"B"})
+	}
+}
+
+func TestCachingFetcherPlain(t *testing.T) {
+	site := fakeFetcher{
+		"root": &fakeResult{"root", []string{"a", "b"}},
+		"a":    &fakeResult{"A", nil},
+		"b":    &fakeResult{"B", nil},
+	}
+	f := NewCachingFetcher(site, nil)
+	if _, err := NewCrawler(WithContext(f)).Run(context.Background(), "root", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without validators every page is fetched again
+	// and compared with the cached copy.
+	site["a"] = &fakeResult{"A, edited", nil}
+	site["b"] = &fakeResult{"B", []string{"root"}}
+	cf := newCountingFetcher(site)
+	f = NewCachingFetcher(cf, f.Cache())
+	if _, err := NewCrawler(WithContext(f)).Run(context.Background(), "root", 2); err != nil {
+		t.Fatal(err)
+	}
+	want := ChangeReport{Changed: []string{"a", "b"}, Unchanged: []string{"root"}}
+	if got := f.Report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Report() = %+v, want %+v", got, want)
+	}
+	if len(cf.counts) != 3 {
+		t.Errorf("fetched %d pages, want 3", len(cf.counts))
+	}
+}
+
+func TestCachingFetcherKeepsPagesOnError(t *testing.T) {
+	prev := &FetchCache{Pages: map[string]*CachedPage{
+		"https://golang.org/cmd/": {Body: "Commands"},
+		"https://golang.org/pkg/": {Body: "Packages"},
+	}}
+	f := NewCachingFetcher(fetcher, prev)
+	if _, _, err := f.Fetch("https://golang.org/cmd/"); err == nil {
+		t.Fatal("Fetch() of a missing page succeeded")
+	}
+	if p := f.Cache().Pages["https://golang.org/cmd/"]; p == nil || p.Body != "Commands" {
+		t.Errorf("Cache() lost the page that failed, got %+v", p)
+	}
+	want := ChangeReport{Removed: []string{"https://golang.org/pkg/"}}
+	if got := f.Report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Report() = %+v, want %+v", got, want)
+	}
+}
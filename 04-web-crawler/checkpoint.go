@@ -0,0 +1,141 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// checkpointFile is the JSON form of a crawlState. Edges are not
+// saved, they are rebuilt from the pages' links.
+type checkpointFile struct {
+	Root     string           `json:"root"`
+	Depth    int              `json:"depth"`
+	Frontier []checkpointTask `json:"frontier"`
+	Pending  []checkpointTask `json:"pending,omitempty"`
+	Queued   []string         `json:"queued"`
+	PerHost  map[string]int   `json:"per_host"`
+	Pages    []*Page          `json:"pages"`
+	Filtered []Filtered       `json:"filtered,omitempty"`
+}
+
+type checkpointTask struct {
+	URL    string `json:"url"`
+	Parent string `json:"parent,omitempty"`
+	Depth  int    `json:"depth"`
+}
+
+// checkpoint saves s to c.Checkpoint, if set. Pages in flight, pages
+// a cancel cut short and the claimed next task are saved as pending,
+// since they are not done yet; Resume fetches them first. The file is
+// replaced atomically, so a crash while saving leaves the previous
+// checkpoint intact.
+func (c *Crawler) checkpoint(s *crawlState, next task, hasNext bool) error {
+	if c.Checkpoint == "" {
+		return nil
+	}
+	cf := checkpointFile{
+		Root:     s.root,
+		Depth:    s.depth,
+		PerHost:  s.perHost,
+		Filtered: s.res.Filtered,
+	}
+	for _, p := range s.res.Pages {
+		if _, ok := s.cut[p.URL]; !ok {
+			cf.Pages = append(cf.Pages, p)
+		}
+	}
+	var pending []task
+	for _, t := range s.inFlight {
+		pending = append(pending, t)
+	}
+	for _, t := range s.cut {
+		pending = append(pending, t)
+	}
+	slices.SortFunc(pending, func(a, b task) int {
+		return cmp.Or(cmp.Compare(a.depth, b.depth), cmp.Compare(a.url, b.url))
+	})
+	if hasNext {
+		pending = append(pending, next)
+	}
+	for _, t := range pending {
+		cf.Pending = append(cf.Pending, checkpointTask{URL: t.url, Parent: t.parent, Depth: t.depth})
+	}
+	for _, t := range s.frontier.tasks() {
+		cf.Frontier = append(cf.Frontier, checkpointTask{URL: t.url, Parent: t.parent, Depth: t.depth})
+	}
+	for u := range s.queued {
+		cf.Queued = append(cf.Queued, u)
+	}
+
+	return writeFileAtomic(c.Checkpoint, cf)
+}
+
+// writeFileAtomic writes v to path as JSON. It writes a temporary file
+// next to path and renames it over path, so a crash while writing
+// leaves the previous file intact.
+func writeFileAtomic(path string, v any) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(v); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Resume carries on with the crawl saved in the checkpoint at path.
+// Pages the checkpoint holds are not fetched again; the URLs that were
+// waiting or in flight when it was saved are. The result holds the
+// pages of both runs.
+func (c *Crawler) Resume(ctx context.Context, path string) (*CrawlResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cf checkpointFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return nil, err
+	}
+
+	s := &crawlState{
+		root:     cf.Root,
+		depth:    cf.Depth,
+		frontier: c.newFrontier(),
+		queued:   make(map[string]bool),
+		perHost:  cf.PerHost,
+		inFlight: make(map[string]task),
+		cut:      make(map[string]task),
+		res:      &CrawlResult{Filtered: cf.Filtered},
+	}
+	if s.perHost == nil {
+		s.perHost = make(map[string]int)
+	}
+	for _, u := range cf.Queued {
+		s.queued[u] = true
+	}
+	for _, t := range cf.Frontier {
+		s.frontier.push(task{url: t.URL, parent: t.Parent, depth: t.Depth})
+	}
+	// Requeue backwards, so the first pending task is taken first.
+	for _, t := range slices.Backward(cf.Pending) {
+		s.frontier.requeue(task{url: t.URL, parent: t.Parent, depth: t.Depth})
+	}
+	for _, p := range cf.Pages {
+		c.visited.claim(p.URL)
+		if p.DuplicateOf == "" {
+			c.dedupe(s, p)
+		}
+		s.res.add(p)
+	}
+	return c.run(ctx, s)
+}
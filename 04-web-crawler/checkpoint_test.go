@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// crashingFetcher counts fetches and calls crash on the n-th one,
+// which is cut short by it.
+type crashingFetcher struct {
+	*countingFetcher
+	n     int
+	crash func()
+
+	mu      sync.Mutex
+	fetches int
+}
+
+func (f *crashingFetcher) Fetch(ctx context.Context, url string) (string, []string, error) {
+	f.mu.Lock()
+	f.fetches++
+	crash := f.fetches == f.n
+	f.mu.Unlock()
+	if crash {
+		f.crash()
+		return "", nil, ctx.Err()
+	}
+	return WithContext(f.countingFetcher).Fetch(ctx, url)
+}
+
+func TestCheckpointResume(t *testing.T) {
+	site := wideSite(30)
+	path := filepath.Join(t.TempDir(), "crawl.json")
+
+	// First run: cancelled during the 13th fetch, having saved a
+	// checkpoint every 5 pages. The last checkpoint, saved on the
+	// way out, holds the 12 pages fetched before.
+	ctx, crash := context.WithCancel(context.Background())
+	defer crash()
+	first := &crashingFetcher{countingFetcher: newCountingFetcher(site), n: 13, crash: crash}
+	c := NewCrawler(first)
+	c.Concurrency = 2
+	c.Checkpoint = path
+	c.CheckpointEvery = 5
+	if _, err := c.Run(ctx, "root", 3); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cf checkpointFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		t.Fatal(err)
+	}
+	if len(cf.Pages) != 12 {
+		t.Fatalf("checkpoint holds %d pages, want 12", len(cf.Pages))
+	}
+	for _, p := range cf.Pages {
+		if p.Err != nil {
+			t.Errorf("checkpoint holds %s as done, with error %v", p.URL, p.Err)
+		}
+	}
+	if len(cf.Pending) == 0 {
+		t.Errorf("checkpoint has no pending pages, want the one cut short")
+	}
+
+	// Second run: a fresh Crawler picks up the checkpoint.
+	second := newCountingFetcher(site)
+	c = NewCrawler(WithContext(second))
+	c.Concurrency = 2
+	res, err := c.Resume(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Pages) != 31 {
+		t.Errorf("Resume() result has %d pages, want 31", len(res.Pages))
+	}
+	if errs := res.Errors(); len(errs) != 0 {
+		t.Errorf("Resume() result has errors %v", errs)
+	}
+	for _, p := range cf.Pages {
+		if n := second.counts[p.URL]; n != 0 {
+			t.Errorf("%s was in the checkpoint but fetched %d times on resume", p.URL, n)
+		}
+	}
+	for url, n := range second.counts {
+		if n != 1 {
+			t.Errorf("%s fetched %d times on resume, want 1", url, n)
+		}
+	}
+	if got, want := len(cf.Pages)+len(second.counts), 31; got != want {
+		t.Errorf("checkpointed and resumed pages add up to %d, want %d", got, want)
+	}
+}
+
+func TestCheckpointSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.json")
+	sig := make(chan os.Signal, 1)
+	sig <- syscall.SIGUSR1
+
+	c := NewCrawler(WithContext(fetcher))
+	c.Checkpoint = path
+	c.CheckpointSignal = sig
+	res, err := c.Run(context.Background(), "https://golang.org/", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Pages) != 5 {
+		t.Errorf("Run() got %d pages, want 5", len(res.Pages))
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("no checkpoint after signal: %v", err)
+	}
+}
+
+func TestCheckpointSaveError(t *testing.T) {
+	c := NewCrawler(WithContext(fetcher))
+	c.Checkpoint = filepath.Join(t.TempDir(), "missing", "crawl.json")
+	c.CheckpointEvery = 1
+	if _, err := c.Run(context.Background(), "https://golang.org/", 4); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Run() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestCheckpointOnStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.json")
+	stop := errors.New("stop")
+	cf := newCountingFetcher(wideSite(10))
+	c := NewCrawler(WithContext(cf))
+	c.Concurrency = 1
+	c.Checkpoint = path
+	c.OnPage = func(p Page) error {
+		if p.URL == "page/2" {
+			return stop
+		}
+		return nil
+	}
+	if _, err := c.Run(context.Background(), "root", 3); !errors.Is(err, stop) {
+		t.Fatalf("Run() error = %v, want %v", err, stop)
+	}
+
+	// The same Crawler resumes: the task it held when it stopped
+	// was given back, so every page is fetched once.
+	c.OnPage = nil
+	res, err := c.Resume(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Pages) != 11 {
+		t.Errorf("Resume() result has %d pages, want 11", len(res.Pages))
+	}
+	for url, n := range cf.counts {
+		if n != 1 {
+			t.Errorf("%s fetched %d times, want 1", url, n)
+		}
+	}
+	if len(cf.counts) != 11 {
+		t.Errorf("fetched %d pages, want 11", len(cf.counts))
+	}
+}
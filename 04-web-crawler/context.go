@@ -0,0 +1,47 @@
+package main
+
+import "context"
+
+// ContextFetcher is a Fetcher that can be cancelled.
+type ContextFetcher interface {
+	// Fetch returns the body of URL and
+	// a slice of URLs found on that page.
+	Fetch(ctx context.Context, url string) (body string, urls []string, err error)
+}
+
+// WithContext adapts f to a ContextFetcher. If f has a FetchContext
+// method, as HTTPFetcher does, the adapter calls it. Otherwise f
+// cannot be interrupted and the adapter only refuses to start a
+// fetch once ctx is done.
+func WithContext(f Fetcher) ContextFetcher {
+	if cf, ok := f.(interface {
+		FetchContext(ctx context.Context, url string) (string, []string, error)
+	}); ok {
+		return fetchContextFunc(cf.FetchContext)
+	}
+	return contextFetcher{f}
+}
+
+type fetchContextFunc func(ctx context.Context, url string) (string, []string, error)
+
+func (f fetchContextFunc) Fetch(ctx context.Context, url string) (string, []string, error) {
+	return f(ctx, url)
+}
+
+type contextFetcher struct {
+	Fetcher
+}
+
+func (f contextFetcher) Fetch(ctx context.Context, url string) (string, []string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	return f.Fetcher.Fetch(url)
+}
+
+// CrawlContext is Crawl with a context. Once ctx is done no new
+// fetches start; the ones in flight are waited for and the bodies
+// gathered so far are returned together with ctx.Err().
+func CrawlContext(ctx context.Context, url string, depth int, fetcher ContextFetcher) ([]string, error) {
+	return NewCrawler(fetcher).CrawlContext(ctx, url, depth)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// cancellingFetcher cancels the crawl when it fetches url,
+// but still returns that page.
+type cancellingFetcher struct {
+	*countingFetcher
+	url    string
+	cancel context.CancelFunc
+}
+
+func (f cancellingFetcher) Fetch(ctx context.Context, url string) (string, []string, error) {
+	if url == f.url {
+		f.cancel()
+	}
+	return f.countingFetcher.Fetch(url)
+}
+
+// slowFetcher takes delay for every page except the root.
+type slowFetcher struct {
+	Fetcher
+	delay time.Duration
+}
+
+func (f slowFetcher) Fetch(ctx context.Context, url string) (string, []string, error) {
+	if url != "https://golang.org/" {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		}
+	}
+	return f.Fetcher.Fetch(url)
+}
+
+func TestCrawlContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cf := newCountingFetcher(fetcher)
+	f := cancellingFetcher{cf, "https://golang.org/pkg/", cancel}
+
+	res, err := CrawlContext(ctx, "https://golang.org/", 4, f)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CrawlContext() error = %v, want %v", err, context.Canceled)
+	}
+	sort.Strings(res)
+	want := []string{"Packages", "The Go Programming Language"}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("Wrong result. Expected: %+q, Got: %+q", want, res)
+	}
+	for _, url := range []string{"https://golang.org/pkg/fmt/", "https://golang.org/pkg/os/"} {
+		if n := cf.counts[url]; n != 0 {
+			t.Errorf("%s fetched %d times after cancel, want 0", url, n)
+		}
+	}
+}
+
+func TestCrawlContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	res, err := CrawlContext(ctx, "https://golang.org/", 4, slowFetcher{fetcher, time.Second})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CrawlContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	want := []string{"The Go Programming Language"}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("Wrong result. Expected: %+q, Got: %+q", want, res)
+	}
+}
+
+func TestCrawlContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cf := newCountingFetcher(fetcher)
+
+	res, err := CrawlContext(ctx, "https://golang.org/", 4, WithContext(cf))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CrawlContext() error = %v, want %v", err, context.Canceled)
+	}
+	if len(res) != 0 || len(cf.counts) != 0 {
+		t.Errorf("CrawlContext() fetched %v pages with a done context, want 0", len(cf.counts))
+	}
+}
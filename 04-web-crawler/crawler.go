@@ -0,0 +1,385 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+// DefaultConcurrency is the number of workers
+// a Crawler uses when Concurrency is not set.
+const DefaultConcurrency = 8
+
+// Crawler crawls pages with a ContextFetcher. Every Crawler keeps its own
+// visited set: crawls on different Crawlers do not interfere, and
+// concurrent crawls on the same Crawler never fetch a URL twice.
+//
+// A crawl keeps the URLs it has yet to fetch in a frontier and hands
+// them to a fixed number of workers, in the crawler's Order, so a wide
+// site does not start a goroutine per link. A Crawler with Concurrency
+// 1 fetches one page at a time, in the same order on every run.
+type Crawler struct {
+	// Concurrency is the number of pages fetched at once.
+	// Zero means DefaultConcurrency.
+	Concurrency int
+	// MaxPages stops a crawl once it has fetched that many pages.
+	// Zero means no limit.
+	MaxPages int
+	// Limiter, if set, is acquired for the page's host
+	// before every fetch and released after it. If it is a
+	// ratelimit.Observer it is told how every fetch went, and if it
+	// keeps a rate history, like ratelimit.Adaptive, the history ends
+	// up in the result's HostRates.
+	Limiter ratelimit.HostLimiter
+	// Policy decides which links are followed.
+	Policy Policy
+	// Order is the order the frontier is fetched in, BFS by default.
+	// Score ranks URLs for the Priority order, higher first; nil
+	// means shallower pages first.
+	Order Order
+	Score func(url string, depth int) float64
+	// Dedup, if set, marks pages whose body duplicates that of an
+	// earlier page, see CrawlResult.Duplicates. Their links are
+	// not followed.
+	Dedup *Dedup
+	// OnPage, if set, is called with every page as soon as it is
+	// fetched, from the goroutine running the crawl. No new fetches
+	// are handed out while it runs, so a slow OnPage slows the crawl
+	// down. If it returns an error the crawl stops with that error.
+	// Once a crawl is stopping OnPage is not called again.
+	OnPage func(Page) error
+	// Checkpoint, if set, is the file a crawl saves its state to,
+	// every CheckpointEvery pages (if not zero), whenever
+	// CheckpointSignal delivers and once more when the crawl is
+	// cancelled or stops with an error. Resume carries on from such a
+	// file. A failed save stops the crawl with its error.
+	Checkpoint       string
+	CheckpointEvery  int
+	CheckpointSignal <-chan os.Signal
+
+	fetcher ContextFetcher
+	visited *visited
+}
+
+func NewCrawler(fetcher ContextFetcher) *Crawler {
+	return &Crawler{
+		fetcher: fetcher,
+		visited: &visited{m: make(map[string]bool)},
+	}
+}
+
+// Crawl fetches url and the pages it links to, in parallel, to a
+// maximum of depth and returns their bodies. Only an error fetching
+// url itself is returned; pages that fail further down are skipped.
+// URLs already claimed by this Crawler are not fetched again.
+func (c *Crawler) Crawl(url string, depth int) ([]string, error) {
+	return c.CrawlContext(context.Background(), url, depth)
+}
+
+// CrawlContext is Crawl with a context, see the CrawlContext function.
+func (c *Crawler) CrawlContext(ctx context.Context, url string, depth int) ([]string, error) {
+	res, err := c.Run(ctx, url, depth)
+	if root := res.Page(url); root != nil && root.Err != nil {
+		return nil, root.Err
+	}
+	return res.Bodies(), err
+}
+
+// Run crawls like CrawlContext but returns every page it tried to
+// fetch, failed ones included, along with the links between them.
+// The error is ctx.Err(); fetch errors are recorded on each Page.
+func (c *Crawler) Run(ctx context.Context, url string, depth int) (*CrawlResult, error) {
+	return c.run(ctx, c.newCrawlState(url, depth))
+}
+
+// crawlState is everything a crawl needs to carry on,
+// which is what a checkpoint saves and Resume loads.
+type crawlState struct {
+	root     string
+	depth    int
+	frontier frontier
+	queued   map[string]bool // URLs ever put in the frontier or filtered
+	perHost  map[string]int  // queued URLs per host
+	inFlight map[string]task // handed to a worker, not back yet
+	cut      map[string]task // cut short by a cancel, to fetch again
+	res      *CrawlResult
+	dedup    dedupIndex
+}
+
+func (c *Crawler) newCrawlState(root string, depth int) *crawlState {
+	s := &crawlState{
+		root:     root,
+		depth:    depth,
+		frontier: c.newFrontier(),
+		queued:   map[string]bool{root: true},
+		perHost:  map[string]int{hostname(root): 1},
+		inFlight: map[string]task{},
+		cut:      map[string]task{},
+		res:      &CrawlResult{},
+	}
+	if depth > 0 {
+		s.frontier.push(task{url: root})
+	}
+	return s
+}
+
+func (c *Crawler) run(ctx context.Context, s *crawlState) (*CrawlResult, error) {
+	work := make(chan task)
+	defer close(work)
+	pages := make(chan *Page)
+	for range c.workers() {
+		go func() {
+			for t := range work {
+				pages <- c.fetch(ctx, t)
+			}
+		}()
+	}
+
+	// The loop below is the only goroutine touching s. The crawl is
+	// over when the frontier is empty (or no more pages may be
+	// fetched) and no worker is busy.
+	var (
+		next       task // claimed, waiting for a free worker
+		hasNext    bool
+		fetched    = len(s.res.Pages)
+		unsaved    int // pages since the last checkpoint
+		stopErr    error
+		saveFailed bool
+		done       = ctx.Done()
+	)
+	stopped := func() bool { return ctx.Err() != nil || stopErr != nil }
+	// save checkpoints s, unless the crawl is stopping: pages still in
+	// flight may yet be cut short, so the one checkpoint to take is
+	// the last, once they are all back.
+	save := func() {
+		if !stopped() {
+			stopErr = c.checkpoint(s, next, hasNext)
+			saveFailed = stopErr != nil
+		}
+		unsaved = 0
+	}
+	for {
+		if stopped() || c.MaxPages > 0 && fetched >= c.MaxPages {
+			if hasNext {
+				// next will not be fetched now: give its URL
+				// back, so a later crawl can, and put it back in
+				// the frontier for the last checkpoint.
+				c.visited.release(next.url)
+				s.frontier.requeue(next)
+				hasNext = false
+			}
+		} else if !hasNext && len(s.inFlight) < c.workers() {
+			// Take the next task only once a worker is free, so it
+			// is chosen knowing every link found so far.
+			next, hasNext = c.pop(s)
+		}
+		if !hasNext && len(s.inFlight) == 0 {
+			if h, ok := c.Limiter.(interface {
+				History() map[string][]ratelimit.RateChange
+			}); ok {
+				s.res.HostRates = h.History()
+			}
+			if stopped() && !saveFailed {
+				// Save where the crawl stopped, so that Resume does
+				// not fetch again what was fetched since the last
+				// checkpoint.
+				if err := c.checkpoint(s, task{}, false); err != nil {
+					return s.res, errors.Join(cmp.Or(stopErr, ctx.Err()), err)
+				}
+			}
+			if stopErr != nil {
+				return s.res, stopErr
+			}
+			return s.res, ctx.Err()
+		}
+
+		var send chan task
+		if hasNext {
+			send = work
+		}
+		select {
+		case send <- next:
+			s.inFlight[next.url] = next
+			hasNext = false
+			fetched++
+		case p := <-pages:
+			if ctx.Err() != nil && errors.Is(p.Err, ctx.Err()) {
+				// The cancel cut p short: give its URL back, and
+				// checkpoint it as pending rather than done.
+				c.visited.release(p.URL)
+				s.cut[p.URL] = s.inFlight[p.URL]
+			}
+			delete(s.inFlight, p.URL)
+			c.dedupe(s, p)
+			s.res.add(p)
+			if c.OnPage != nil && !stopped() {
+				stopErr = c.OnPage(*p)
+			}
+			c.expand(s, p)
+			if unsaved++; c.CheckpointEvery > 0 && unsaved >= c.CheckpointEvery {
+				save()
+			}
+		case <-c.CheckpointSignal:
+			save()
+		case <-done:
+			// Stop selecting on done, the top of the loop
+			// drops the frontier from now on.
+			done = nil
+		}
+	}
+}
+
+// dedupe sets p.DuplicateOf if p is a duplicate,
+// and otherwise remembers it as an original.
+func (c *Crawler) dedupe(s *crawlState, p *Page) {
+	if c.Dedup != nil && p.Err == nil {
+		if p.fingerprint == nil {
+			p.fingerprint = c.Dedup.fingerprint(p.Body)
+		}
+		p.DuplicateOf = s.dedup.original(p, c.Dedup.Threshold)
+	}
+}
+
+// expand queues the links of p that the Policy lets through.
+func (c *Crawler) expand(s *crawlState, p *Page) {
+	if p.Err != nil || p.DuplicateOf != "" || p.Depth+1 >= s.depth {
+		return
+	}
+	var ts []task
+	for _, link := range p.Links {
+		u := c.Policy.rewrite(link)
+		if s.queued[u] {
+			continue
+		}
+		s.queued[u] = true
+		if rule := c.Policy.reject(s.root, u, s.perHost); rule != "" {
+			s.res.Filtered = append(s.res.Filtered, Filtered{URL: u, From: p.URL, Rule: rule})
+			continue
+		}
+		s.perHost[hostname(u)]++
+		ts = append(ts, task{url: u, parent: p.URL, depth: p.Depth + 1})
+	}
+	s.frontier.push(ts...)
+}
+
+// task is a URL waiting in the frontier.
+type task struct {
+	url    string
+	parent string
+	depth  int
+}
+
+// pop takes tasks from the frontier until it finds one whose URL it
+// can claim. It reports false if the frontier runs out, or, in BFS
+// order, if the next task is deeper than a page still in flight.
+func (c *Crawler) pop(s *crawlState) (task, bool) {
+	for s.frontier.len() > 0 {
+		if c.Order == BFS && s.shallowerInFlight(s.frontier.peek().depth) {
+			break
+		}
+		if t := s.frontier.pop(); c.visited.claim(t.url) {
+			return t, true
+		}
+	}
+	return task{}, false
+}
+
+// shallowerInFlight reports whether a page above depth is in flight.
+func (s *crawlState) shallowerInFlight(depth int) bool {
+	for _, t := range s.inFlight {
+		if t.depth < depth {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Crawler) fetch(ctx context.Context, t task) *Page {
+	host := ratelimit.Host(t.url)
+	// A fetcher with a Check method, like RobotsFetcher, checks the
+	// URL before the limiter is acquired: loading robots.txt may set
+	// a Crawl-delay that this fetch must wait for as well.
+	if ch, ok := c.fetcher.(interface {
+		Check(ctx context.Context, url string) error
+	}); ok {
+		if err := ch.Check(ctx, t.url); err != nil {
+			return &Page{URL: t.url, Depth: t.depth, Parent: t.parent, Err: err}
+		}
+	}
+	if c.Limiter != nil {
+		release, err := c.Limiter.Acquire(ctx, host)
+		if err != nil {
+			return &Page{URL: t.url, Depth: t.depth, Parent: t.parent, Err: err}
+		}
+		defer release()
+	}
+	start := time.Now()
+	body, urls, err := c.fetcher.Fetch(ctx, t.url)
+	if o, ok := c.Limiter.(ratelimit.Observer); ok {
+		o.Observe(host, response(time.Since(start), err))
+	}
+	p := &Page{
+		URL:      t.url,
+		Body:     body,
+		Depth:    t.depth,
+		Parent:   t.parent,
+		Links:    urls,
+		Err:      err,
+		Duration: time.Since(start),
+	}
+	// Fingerprint here rather than in the crawl loop,
+	// so the hashing is spread over the workers.
+	if c.Dedup != nil && err == nil {
+		p.fingerprint = c.Dedup.fingerprint(body)
+	}
+	return p
+}
+
+// response describes a fetch for a ratelimit.Observer.
+func response(latency time.Duration, err error) ratelimit.Response {
+	r := ratelimit.Response{Latency: latency, Err: err}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		r.StatusCode = he.StatusCode
+		r.RetryAfter = he.RetryAfter
+	}
+	return r
+}
+
+func (c *Crawler) workers() int {
+	if c.Concurrency > 0 {
+		return c.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+// visited is a set of URLs that is safe for concurrent use.
+type visited struct {
+	m map[string]bool
+	sync.Mutex
+}
+
+// claim marks url as visited and reports whether this call was the
+// first to do so. Checking and marking happen under one lock, so two
+// goroutines can never both claim the same URL.
+func (v *visited) claim(url string) bool {
+	v.Lock()
+	defer v.Unlock()
+	if v.m[url] {
+		return false
+	}
+	v.m[url] = true
+	return true
+}
+
+// release forgets that url was claimed.
+func (v *visited) release(url string) {
+	v.Lock()
+	defer v.Unlock()
+	delete(v.m, url)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// countingFetcher counts how many times each URL is fetched.
+type countingFetcher struct {
+	Fetcher
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newCountingFetcher(f Fetcher) *countingFetcher {
+	return &countingFetcher{Fetcher: f, counts: make(map[string]int)}
+}
+
+func (f *countingFetcher) Fetch(url string) (string, []string, error) {
+	f.mu.Lock()
+	f.counts[url]++
+	f.mu.Unlock()
+	return f.Fetcher.Fetch(url)
+}
+
+func TestCrawlerConcurrentCrawls(t *testing.T) {
+	cf := newCountingFetcher(fetcher)
+	c := NewCrawler(WithContext(cf))
+
+	roots := []string{
+		"https://golang.org/",
+		"https://golang.org/pkg/",
+		"https://golang.org/pkg/fmt/",
+		"https://golang.org/pkg/os/",
+	}
+	var (
+		mu     sync.Mutex
+		bodies []string
+		wg     sync.WaitGroup
+	)
+	for range 10 {
+		for _, root := range roots {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := c.Crawl(root, 4)
+				if err != nil {
+					t.Error(err)
+				}
+				mu.Lock()
+				bodies = append(bodies, res...)
+				mu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+
+	for url, n := range cf.counts {
+		if n != 1 {
+			t.Errorf("%s fetched %d times, want 1", url, n)
+		}
+	}
+	want := []string{"Package fmt", "Package os", "Packages", "The Go Programming Language"}
+	sort.Strings(bodies)
+	if !reflect.DeepEqual(bodies, want) {
+		t.Errorf("Wrong result. Expected: %+q, Got: %+q", want, bodies)
+	}
+}
+
+func TestCrawlerIndependent(t *testing.T) {
+	want := []string{"Package fmt", "Package os", "Packages", "The Go Programming Language"}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cf := newCountingFetcher(fetcher)
+			res, err := NewCrawler(WithContext(cf)).Crawl("https://golang.org/", 4)
+			if err != nil {
+				t.Error(err)
+			}
+			sort.Strings(res)
+			if !reflect.DeepEqual(res, want) {
+				t.Errorf("Wrong result. Expected: %+q, Got: %+q", want, res)
+			}
+			for url, n := range cf.counts {
+				if n != 1 {
+					t.Errorf("%s fetched %d times, want 1", url, n)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/sha256"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// DefaultShingle is the number of words per shingle
+// a Dedup uses when Shingle is not set.
+const DefaultShingle = 3
+
+// Dedup finds pages whose body is the same as, or close to, that of a
+// page fetched earlier in the crawl: mirrors, and URLs that differ only
+// in parameters. Bodies are compared by SHA-256 for exact duplicates and
+// by a 64-bit SimHash over word shingles for near-duplicates.
+type Dedup struct {
+	// Threshold is the largest number of differing SimHash bits, out
+	// of 64, for two bodies to be near-duplicates. Zero only finds
+	// exact duplicates; around 3 suits pages of a few hundred words.
+	Threshold int
+	// Shingle is the number of consecutive words hashed together.
+	// Zero means DefaultShingle.
+	Shingle int
+}
+
+// fingerprint is what Dedup compares bodies by.
+type fingerprint struct {
+	sum     [sha256.Size]byte
+	simhash uint64
+}
+
+func (d *Dedup) fingerprint(body string) *fingerprint {
+	k := d.Shingle
+	if k <= 0 {
+		k = DefaultShingle
+	}
+	return &fingerprint{sum: sha256.Sum256([]byte(body)), simhash: simhash(body, k)}
+}
+
+// simhash folds the hashes of every run of k words in text into one
+// hash, each bit of which is set if it is set in most of them. Similar
+// texts share most runs, so their simhashes differ in few bits.
+func simhash(text string, k int) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+	k = min(k, len(words))
+	var votes [64]int
+	for i := 0; i+k <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+k], " ")))
+		sum := h.Sum64()
+		for b := range votes {
+			if sum&(1<<b) != 0 {
+				votes[b]++
+			} else {
+				votes[b]--
+			}
+		}
+	}
+	var hash uint64
+	for b, v := range votes {
+		if v > 0 {
+			hash |= 1 << b
+		}
+	}
+	return hash
+}
+
+// dedupIndex holds the fingerprints of the original
+// pages of a crawl, those that are no duplicates.
+type dedupIndex struct {
+	exact map[[sha256.Size]byte]string
+	near  []originalPage
+}
+
+type originalPage struct {
+	url     string
+	simhash uint64
+}
+
+// original returns the URL of the page p duplicates, or, if there is
+// none, adds p to the index and returns "". Near-duplicates are looked
+// for among all originals, so this is linear in their number.
+func (x *dedupIndex) original(p *Page, threshold int) string {
+	if x.exact == nil {
+		x.exact = make(map[[sha256.Size]byte]string)
+	}
+	if u, ok := x.exact[p.fingerprint.sum]; ok {
+		return u
+	}
+	if threshold > 0 {
+		for _, o := range x.near {
+			if bits.OnesCount64(o.simhash^p.fingerprint.simhash) <= threshold {
+				return o.url
+			}
+		}
+	}
+	x.exact[p.fingerprint.sum] = p.URL
+	x.near = append(x.near, originalPage{p.URL, p.fingerprint.simhash})
+	return ""
+}
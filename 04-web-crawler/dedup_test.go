@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"math/bits"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const goText = "Go is an open source programming language that makes it simple to build secure, " +
+	"scalable systems. It was designed at Google to improve programming productivity in an era " +
+	"of multicore, networked machines and large codebases. Its concurrency mechanisms make it " +
+	"easy to write programs that get the most out of multicore and networked machines, while " +
+	"its novel type system enables flexible and modular program construction."
+
+const foxText = "The quick brown fox jumps over the lazy dog while the cat watches from the " +
+	"window and the bird sings in the tree near the old house by the river."
+
+func TestSimhash(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		maxDist int
+		minDist int
+	}{
+		{name: "same text", a: goText, b: goText},
+		{name: "case and punctuation", a: goText, b: strings.ToUpper(strings.ReplaceAll(goText, ",", ""))},
+		{name: "one word changed", a: goText, b: strings.Replace(goText, "secure", "reliable", 1), maxDist: 3},
+		{name: "different text", a: goText, b: foxText, maxDist: 64, minDist: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := bits.OnesCount64(simhash(tt.a, DefaultShingle) ^ simhash(tt.b, DefaultShingle))
+			if d < tt.minDist || d > tt.maxDist {
+				t.Errorf("simhash() distance = %d, want between %d and %d", d, tt.minDist, tt.maxDist)
+			}
+		})
+	}
+}
+
+// mirrorSite has an exact mirror and a near-duplicate of its "go" page.
+// Only those two link to "secret".
+var mirrorSite = fakeFetcher{
+	"root":   &fakeResult{"Home", []string{"go", "mirror", "go?v=2", "fox"}},
+	"go":     &fakeResult{goText, []string{"root"}},
+	"mirror": &fakeResult{goText, []string{"secret"}},
+	"go?v=2": &fakeResult{strings.Replace(goText, "secure", "reliable", 1), []string{"secret"}},
+	"fox":    &fakeResult{foxText, nil},
+	"secret": &fakeResult{"Secret", nil},
+}
+
+func TestCrawlerDedup(t *testing.T) {
+	tests := []struct {
+		name   string
+		dedup  *Dedup
+		dups   [][]string
+		bodies int
+		secret bool
+	}{
+		{
+			name:   "off",
+			bodies: 6,
+			secret: true,
+		},
+		{
+			name:   "exact",
+			dedup:  &Dedup{},
+			dups:   [][]string{{"go", "mirror"}},
+			bodies: 5,
+			secret: true,
+		},
+		{
+			name:   "near",
+			dedup:  &Dedup{Threshold: 3},
+			dups:   [][]string{{"go", "mirror", "go?v=2"}},
+			bodies: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCrawler(WithContext(mirrorSite))
+			c.Concurrency = 1
+			c.Dedup = tt.dedup
+
+			res, err := c.Run(context.Background(), "root", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Duplicates(); !reflect.DeepEqual(got, tt.dups) {
+				t.Errorf("Duplicates() = %q, want %q", got, tt.dups)
+			}
+			if got := len(res.Bodies()); got != tt.bodies {
+				t.Errorf("Bodies() has %d bodies, want %d", got, tt.bodies)
+			}
+			if got := res.Page("secret") != nil; got != tt.secret {
+				t.Errorf("secret fetched = %v, want %v", got, tt.secret)
+			}
+			for _, e := range res.Edges {
+				if dup := res.Page(e.From).DuplicateOf; dup != "" {
+					t.Errorf("Edges has %s -> %s, from a duplicate of %s", e.From, e.To, dup)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawlerDedupResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.json")
+	ctx, crash := context.WithCancel(context.Background())
+	defer crash()
+
+	// Save after "mirror" and die fetching "go?v=2".
+	c := NewCrawler(&crashingFetcher{countingFetcher: newCountingFetcher(mirrorSite), n: 4, crash: crash})
+	c.Concurrency = 1
+	c.Dedup = &Dedup{Threshold: 3}
+	c.Checkpoint = path
+	c.CheckpointEvery = 3
+	c.Run(ctx, "root", 3)
+
+	c = NewCrawler(WithContext(mirrorSite))
+	c.Concurrency = 1
+	c.Dedup = &Dedup{Threshold: 3}
+	res, err := c.Resume(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range res.Pages {
+		if p.DuplicateOf != "" {
+			got = append(got, p.URL)
+		}
+	}
+	sort.Strings(got)
+	if want := []string{"go?v=2", "mirror"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Resume() duplicates = %q, want %q", got, want)
+	}
+}
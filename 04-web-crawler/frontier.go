@@ -0,0 +1,159 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+)
+
+// Order is the order in which a crawl fetches the URLs in its
+// frontier. With Concurrency 1 the order is exact and the same on
+// every run; with more workers pages are still handed out in this
+// order but may finish in any order.
+type Order int
+
+const (
+	// BFS fetches level by level: no page at depth d+1 is handed out
+	// until every page at depth d is done.
+	BFS Order = iota
+	// DFS follows the most recently found link first, and the links
+	// of one page in the order they appear.
+	DFS
+	// Priority fetches the URL with the highest Crawler.Score first,
+	// and URLs with the same score in the order they were found.
+	Priority
+)
+
+func (o Order) String() string {
+	switch o {
+	case BFS:
+		return "bfs"
+	case DFS:
+		return "dfs"
+	case Priority:
+		return "priority"
+	}
+	return fmt.Sprintf("Order(%d)", int(o))
+}
+
+// frontier holds the tasks of a crawl waiting to be fetched.
+type frontier interface {
+	// push adds the links found on one page, in page order.
+	push(ts ...task)
+	// requeue puts back a task that was taken but not fetched,
+	// so that it is taken next.
+	requeue(t task)
+	// pop takes the next task; peek only looks at it.
+	pop() task
+	peek() task
+	len() int
+	// tasks returns the tasks in the order they were pushed,
+	// so pushing them again rebuilds the frontier.
+	tasks() []task
+}
+
+func (c *Crawler) newFrontier() frontier {
+	switch c.Order {
+	case DFS:
+		return &stack{}
+	case Priority:
+		score := c.Score
+		if score == nil {
+			score = func(url string, depth int) float64 { return -float64(depth) }
+		}
+		return &priorityQueue{score: score}
+	}
+	return &queue{}
+}
+
+// queue is a first-in first-out frontier.
+type queue struct{ ts []task }
+
+func (q *queue) push(ts ...task) { q.ts = append(q.ts, ts...) }
+func (q *queue) requeue(t task)  { q.ts = slices.Insert(q.ts, 0, t) }
+func (q *queue) pop() task {
+	t := q.ts[0]
+	q.ts = q.ts[1:]
+	return t
+}
+func (q *queue) peek() task    { return q.ts[0] }
+func (q *queue) len() int      { return len(q.ts) }
+func (q *queue) tasks() []task { return slices.Clone(q.ts) }
+
+// stack is a last-in first-out frontier.
+type stack struct{ ts []task }
+
+// push pushes ts in reverse, so the first link is taken first.
+func (s *stack) push(ts ...task) {
+	for i := len(ts) - 1; i >= 0; i-- {
+		s.ts = append(s.ts, ts[i])
+	}
+}
+func (s *stack) requeue(t task) { s.ts = append(s.ts, t) }
+func (s *stack) pop() task {
+	t := s.ts[len(s.ts)-1]
+	s.ts = s.ts[:len(s.ts)-1]
+	return t
+}
+func (s *stack) peek() task    { return s.ts[len(s.ts)-1] }
+func (s *stack) len() int      { return len(s.ts) }
+func (s *stack) tasks() []task { return slices.Clone(s.ts) }
+
+// priorityQueue takes the task with the highest score first.
+type priorityQueue struct {
+	score func(url string, depth int) float64
+	h     taskHeap
+	seq   int
+}
+
+func (q *priorityQueue) push(ts ...task) {
+	for _, t := range ts {
+		q.seq++
+		heap.Push(&q.h, scoredTask{t, q.score(t.url, t.depth), q.seq})
+	}
+}
+
+func (q *priorityQueue) requeue(t task) {
+	// Jump the queue among tasks with the same score.
+	q.seq++
+	heap.Push(&q.h, scoredTask{t, q.score(t.url, t.depth), -q.seq})
+}
+
+func (q *priorityQueue) pop() task  { return heap.Pop(&q.h).(scoredTask).task }
+func (q *priorityQueue) peek() task { return q.h[0].task }
+func (q *priorityQueue) len() int   { return len(q.h) }
+func (q *priorityQueue) tasks() []task {
+	h := slices.Clone(q.h)
+	slices.SortFunc(h, func(a, b scoredTask) int {
+		return a.seq - b.seq
+	})
+	ts := make([]task, len(h))
+	for i, st := range h {
+		ts[i] = st.task
+	}
+	return ts
+}
+
+type scoredTask struct {
+	task
+	score float64
+	seq   int
+}
+
+type taskHeap []scoredTask
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score > h[j].score
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x any)   { *h = append(*h, x.(scoredTask)) }
+func (h *taskHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// treeSite is a binary tree of pages levels deep: "n" links to "n0"
+// and "n1", "n0" to "n00" and "n01" and so on.
+func treeSite(levels int) fakeFetcher {
+	f := fakeFetcher{}
+	var add func(u string, level int)
+	add = func(u string, level int) {
+		r := &fakeResult{body: u}
+		f[u] = r
+		if level == levels {
+			return
+		}
+		for _, c := range []string{u + "0", u + "1"} {
+			r.urls = append(r.urls, c)
+			add(c, level+1)
+		}
+	}
+	add("n", 1)
+	return f
+}
+
+func urls(res *CrawlResult) []string {
+	var us []string
+	for _, p := range res.Pages {
+		us = append(us, p.URL)
+	}
+	return us
+}
+
+func TestCrawlerOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		order Order
+		score func(url string, depth int) float64
+		want  []string
+	}{
+		{
+			name:  "bfs",
+			order: BFS,
+			want:  []string{"n", "n0", "n1", "n00", "n01", "n10", "n11"},
+		},
+		{
+			name:  "dfs",
+			order: DFS,
+			want:  []string{"n", "n0", "n00", "n01", "n1", "n10", "n11"},
+		},
+		{
+			name:  "priority defaults to shallow first",
+			order: Priority,
+			want:  []string{"n", "n0", "n1", "n00", "n01", "n10", "n11"},
+		},
+		{
+			name:  "priority by score",
+			order: Priority,
+			score: func(url string, depth int) float64 {
+				return float64(strings.Count(url, "1"))
+			},
+			want: []string{"n", "n1", "n11", "n10", "n0", "n01", "n00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCrawler(WithContext(treeSite(3)))
+			c.Concurrency = 1
+			c.Order = tt.order
+			c.Score = tt.score
+
+			res, err := c.Run(context.Background(), "n", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := urls(res); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// logFetcher logs the start and end of every fetch,
+// and sleeps before returning the slow URL.
+type logFetcher struct {
+	Fetcher
+	slow string
+
+	mu  sync.Mutex
+	log []string
+}
+
+func (f *logFetcher) Fetch(url string) (string, []string, error) {
+	f.record("start " + url)
+	if url == f.slow {
+		time.Sleep(20 * time.Millisecond)
+	}
+	defer f.record("end " + url)
+	return f.Fetcher.Fetch(url)
+}
+
+func (f *logFetcher) record(event string) {
+	f.mu.Lock()
+	f.log = append(f.log, event)
+	f.mu.Unlock()
+}
+
+func TestCrawlerStrictBFS(t *testing.T) {
+	f := &logFetcher{Fetcher: treeSite(3), slow: "n1"}
+	c := NewCrawler(WithContext(f))
+	c.Concurrency = 4
+
+	if _, err := c.Run(context.Background(), "n", 3); err != nil {
+		t.Fatal(err)
+	}
+	end := slices.Index(f.log, "end n1")
+	for _, u := range []string{"n00", "n01", "n10", "n11"} {
+		if start := slices.Index(f.log, "start "+u); start < end {
+			t.Errorf("%s started before n1, at depth 1, was done: %v", u, f.log)
+		}
+	}
+}
+
+func TestCrawlerOrderResume(t *testing.T) {
+	for _, order := range []Order{BFS, DFS, Priority} {
+		t.Run(order.String(), func(t *testing.T) {
+			site := treeSite(4)
+			c := NewCrawler(WithContext(site))
+			c.Concurrency = 1
+			c.Order = order
+			res, err := c.Run(context.Background(), "n", 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := urls(res)
+
+			// Save every 2 pages and die on the 5th, so the
+			// checkpoint holds 4 pages and the 5th is in flight.
+			path := filepath.Join(t.TempDir(), "crawl.json")
+			ctx, crash := context.WithCancel(context.Background())
+			defer crash()
+			c = NewCrawler(&crashingFetcher{countingFetcher: newCountingFetcher(site), n: 5, crash: crash})
+			c.Concurrency = 1
+			c.Order = order
+			c.Checkpoint = path
+			c.CheckpointEvery = 2
+			if _, err := c.Run(ctx, "n", 4); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+			}
+
+			c = NewCrawler(WithContext(site))
+			c.Concurrency = 1
+			c.Order = order
+			res, err = c.Resume(context.Background(), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := urls(res); !reflect.DeepEqual(got, want) {
+				t.Errorf("Resume() order = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxBodySize is the number of body bytes
+// an HTTPFetcher reads when MaxBodySize is not set.
+const DefaultMaxBodySize = 1 << 20
+
+// HTTPFetcher is a Fetcher that fetches pages over HTTP. HTML pages
+// are scanned for <a href> links, which are resolved against the page
+// (after redirects) and normalised with Normalize. Other text pages
+// are returned without links; any other content type is a
+// *ContentTypeError.
+// WithContext uses its FetchContext method, so crawls can cancel it,
+// and CachingFetcher its FetchIfChanged method.
+type HTTPFetcher struct {
+	// Client sends the requests. Nil means http.DefaultClient.
+	Client *http.Client
+	// UserAgent, if set, is sent with every request.
+	UserAgent string
+	// MaxBodySize caps how much of a body is read; longer bodies are
+	// truncated. Zero means DefaultMaxBodySize.
+	MaxBodySize int64
+}
+
+func (f *HTTPFetcher) Fetch(url string) (string, []string, error) {
+	return f.FetchContext(context.Background(), url)
+}
+
+func (f *HTTPFetcher) FetchContext(ctx context.Context, url string) (string, []string, error) {
+	body, urls, _, err := f.FetchIfChanged(ctx, url, Validators{})
+	return body, urls, err
+}
+
+// FetchIfChanged sends v as If-None-Match and If-Modified-Since, and
+// returns ErrNotModified if the server answers 304 Not Modified. It
+// returns the validators of the page it fetched.
+func (f *HTTPFetcher) FetchIfChanged(ctx context.Context, url string, v Validators) (string, []string, Validators, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, Validators{}, err
+	}
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil && resp != nil {
+		// The client's CheckRedirect refused a redirect. The server
+		// did answer, so return the refusal itself rather than the
+		// *url.Error around it, which reads as a transport failure.
+		return "", nil, Validators{}, errors.Unwrap(err)
+	}
+	if err != nil {
+		return "", nil, Validators{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return "", nil, v, ErrNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", nil, Validators{}, newHTTPError(url, resp)
+	}
+	nv := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	limit := f.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return "", nil, Validators{}, err
+	}
+	body := string(b)
+
+	ct := resp.Header.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(b)
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", nil, Validators{}, &ContentTypeError{URL: url, ContentType: ct, Body: body}
+	}
+	switch {
+	case mt == "text/html" || mt == "application/xhtml+xml":
+		return body, extractLinks(resp.Request.URL, body), nv, nil
+	case strings.HasPrefix(mt, "text/"):
+		return body, nil, nv, nil
+	}
+	return "", nil, Validators{}, &ContentTypeError{URL: url, ContentType: mt, Body: body}
+}
+
+// HTTPError is returned by HTTPFetcher for a response
+// with a status code other than 2xx.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is the delay asked for by a Retry-After
+	// header, or zero if there was none.
+	RetryAfter time.Duration
+}
+
+func newHTTPError(url string, resp *http.Response) *HTTPError {
+	e := &HTTPError{URL: url, StatusCode: resp.StatusCode}
+	if ra := resp.Header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(ra); err == nil {
+			e.RetryAfter = max(time.Until(t), 0)
+		}
+	}
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// ContentTypeError is returned by HTTPFetcher for a page whose content
+// type it does not read. Body is the page all the same, for callers
+// that know what it holds, like RobotsFetcher.
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+	Body        string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("%s: unsupported content type %q", e.URL, e.ContentType)
+}
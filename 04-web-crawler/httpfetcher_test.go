@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSite serves a small site over HTTP.
+func testSite() *httptest.Server {
+	mux := http.NewServeMux()
+	page := func(path, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+		})
+	}
+	page("/{$}", `<html><body>
+		<a href="/about/">About</a>
+		<a href="about#team">Team</a>
+		<a href="docs">Docs</a>
+		<a href="mailto:gopher@golang.org">Mail</a>
+		<a href="/notes.txt">Notes</a>
+		<a href="/logo.png">Logo</a>
+		<a href="/missing">Missing</a>
+	</body></html>`)
+	page("/about", `<a href="../">Home</a><a href="/old">Old</a>`)
+	page("/docs/", `<a href="guide">Guide</a><a href="..">Up</a>`)
+	page("/docs/guide", `<p>The guide.</p>`)
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs/", http.StatusFound)
+	})
+	mux.HandleFunc("/notes.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(`<a href="/not-a-link">`))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/near">` + strings.Repeat(" ", 100) + `<a href="/far">`))
+	})
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/agent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.UserAgent()))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	ts := testSite()
+	defer ts.Close()
+	f := &HTTPFetcher{MaxBodySize: 64, UserAgent: "gopher-crawler"}
+
+	tests := []struct {
+		name  string
+		path  string
+		body  string
+		links []string
+		err   bool
+	}{
+		{
+			name:  "relative links resolved and normalised",
+			path:  "/about",
+			links: []string{ts.URL + "/", ts.URL + "/old"},
+		},
+		{
+			name:  "links resolved against redirect target",
+			path:  "/old",
+			links: []string{ts.URL + "/docs/guide", ts.URL + "/"},
+		},
+		{
+			name: "text page has no links",
+			path: "/notes.txt",
+			body: `<a href="/not-a-link">`,
+		},
+		{
+			name:  "body capped",
+			path:  "/big",
+			links: []string{ts.URL + "/near"},
+		},
+		{
+			name: "user agent sent",
+			path: "/agent",
+			body: "gopher-crawler",
+		},
+		{name: "unsupported content type", path: "/logo.png", err: true},
+		{name: "not found", path: "/missing", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, links, err := f.Fetch(ts.URL + tt.path)
+			if (err != nil) != tt.err {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.err)
+			}
+			if tt.body != "" && body != tt.body {
+				t.Errorf("Fetch() body = %q, want %q", body, tt.body)
+			}
+			if int64(len(body)) > f.MaxBodySize {
+				t.Errorf("Fetch() body is %d bytes, want at most %d", len(body), f.MaxBodySize)
+			}
+			if !reflect.DeepEqual(links, tt.links) {
+				t.Errorf("Fetch() links = %q, want %q", links, tt.links)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherStatus(t *testing.T) {
+	ts := testSite()
+	defer ts.Close()
+
+	_, _, err := (&HTTPFetcher{}).Fetch(ts.URL + "/busy")
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("Fetch() error = %v, want *HTTPError", err)
+	}
+	if he.StatusCode != http.StatusServiceUnavailable || he.RetryAfter != 7*time.Second {
+		t.Errorf("Fetch() error = %+v, want status 503 and RetryAfter 7s", he)
+	}
+}
+
+func TestCrawlHTTP(t *testing.T) {
+	ts := testSite()
+	defer ts.Close()
+
+	res, err := NewCrawler(WithContext(&HTTPFetcher{})).Run(context.Background(), ts.URL+"/", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ok, failed []string
+	for _, p := range res.Pages {
+		if p.Err != nil {
+			failed = append(failed, strings.TrimPrefix(p.URL, ts.URL))
+		} else {
+			ok = append(ok, strings.TrimPrefix(p.URL, ts.URL))
+		}
+	}
+	sort.Strings(ok)
+	sort.Strings(failed)
+	if want := []string{"/", "/about", "/docs", "/docs/guide", "/notes.txt", "/old"}; !reflect.DeepEqual(ok, want) {
+		t.Errorf("Run() fetched %q, want %q", ok, want)
+	}
+	if want := []string{"/logo.png", "/missing"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("Run() failed on %q, want %q", failed, want)
+	}
+}
+
+func TestHTTPFetcherCancel(t *testing.T) {
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := WithContext(&HTTPFetcher{}).Fetch(ctx, ts.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
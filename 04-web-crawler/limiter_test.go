@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"concurrency/fetchertest"
+	"concurrency/ratelimit"
+)
+
+// twoHostSite returns a fakeFetcher with n pages on each of two hosts,
+// every page linking to every other page.
+func twoHostSite(n int) fakeFetcher {
+	var urls []string
+	for _, host := range []string{"a.example", "b.example"} {
+		for i := range n {
+			urls = append(urls, fmt.Sprintf("https://%s/%d", host, i))
+		}
+	}
+	f := fakeFetcher{}
+	for _, u := range urls {
+		f[u] = &fakeResult{body: u, urls: urls}
+	}
+	return f
+}
+
+func TestCrawlerPerHostLimit(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := ratelimit.NewFakeClock(start)
+	f := &fetchertest.RecordingFetcher{Fetcher: twoHostSite(10), Clock: clock}
+	c := NewCrawler(WithContext(f))
+	c.Concurrency = 4
+	c.Limiter = ratelimit.NewPerHost(time.Second, 2, 1, clock)
+
+	var (
+		res *CrawlResult
+		err error
+	)
+	clock.Drive(func() { res, err = c.Run(context.Background(), "https://a.example/0", 3) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Pages) != 20 {
+		t.Errorf("Run() fetched %d pages, want 20", len(res.Pages))
+	}
+
+	fetchertest.AssertFetchedOnce(t, f.Fetches())
+	for _, host := range []string{"a.example", "b.example"} {
+		fetches := fetchertest.Filter(f.Fetches(), func(fe fetchertest.Fetch) bool {
+			return ratelimit.Host(fe.URL) == host
+		})
+		// A burst of 2, then one fetch per second.
+		fetchertest.AssertMinSpacing(t, fetches, start, time.Second, 2)
+	}
+}
+
+// failingFetcher fails the listed URLs with their error.
+type failingFetcher struct {
+	Fetcher
+	errs map[string]error
+}
+
+func (f failingFetcher) Fetch(ctx context.Context, url string) (string, []string, error) {
+	if err := f.errs[url]; err != nil {
+		return "", nil, err
+	}
+	return f.Fetcher.Fetch(url)
+}
+
+func TestCrawlerAdaptiveLimit(t *testing.T) {
+	clock := ratelimit.NewFakeClock(time.Unix(0, 0))
+	f := failingFetcher{twoHostSite(5), map[string]error{
+		"https://b.example/3": &HTTPError{URL: "https://b.example/3", StatusCode: 429, RetryAfter: 10 * time.Second},
+	}}
+	c := NewCrawler(f)
+	c.Concurrency = 1
+	c.Order = DFS
+	limiter := ratelimit.NewAdaptive(1, 4, clock)
+	limiter.Increase = 1
+	c.Limiter = limiter
+
+	var (
+		res *CrawlResult
+		err error
+	)
+	clock.Drive(func() { res, err = c.Run(context.Background(), "https://a.example/0", 2) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Errors()) != 1 {
+		t.Errorf("Run() errors = %v, want one", res.Errors())
+	}
+	// a.example answers every time and speeds up to the ceiling;
+	// b.example slows down once it answers 429.
+	tests := []struct {
+		host    string
+		rate    float64
+		reasons []string
+	}{
+		{"a.example", 4, []string{"start", "ok", "ok", "ok"}},
+		{"b.example", 3, []string{"start", "ok", "ok", "ok", "retry-after", "429", "ok"}},
+	}
+	for _, tt := range tests {
+		hist := res.HostRates[tt.host]
+		var reasons []string
+		for _, rc := range hist {
+			reasons = append(reasons, rc.Reason)
+		}
+		if !slices.Equal(reasons, tt.reasons) {
+			t.Errorf("HostRates[%s] reasons = %v, want %v", tt.host, reasons, tt.reasons)
+		}
+		if len(hist) > 0 && hist[len(hist)-1].Rate != tt.rate {
+			t.Errorf("HostRates[%s] ends at %v, want %v", tt.host, hist[len(hist)-1].Rate, tt.rate)
+		}
+	}
+}
+
+func TestCrawlerAdaptivePageErrors(t *testing.T) {
+	clock := ratelimit.NewFakeClock(time.Unix(0, 0))
+	f := failingFetcher{twoHostSite(5), map[string]error{
+		"https://a.example/1": fmt.Errorf("https://a.example/1: %w", ErrDisallowed),
+		"https://a.example/2": errors.New(`https://a.example/2: unsupported content type "image/png"`),
+		"https://b.example/1": &url.Error{Op: "Get", URL: "https://b.example/1", Err: errors.New("connection refused")},
+	}}
+	c := NewCrawler(f)
+	c.Concurrency = 1
+	limiter := ratelimit.NewAdaptive(1, 10, clock)
+	limiter.Increase = 1
+	c.Limiter = limiter
+
+	var (
+		res *CrawlResult
+		err error
+	)
+	clock.Drive(func() { res, err = c.Run(context.Background(), "https://a.example/0", 2) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Disallowed and non-HTML pages say nothing about a.example's
+	// load: only its three good pages change its rate. The refused
+	// connection to b.example slows it down.
+	var reasons []string
+	for _, rc := range res.HostRates["a.example"] {
+		reasons = append(reasons, rc.Reason)
+	}
+	if want := []string{"start", "ok", "ok", "ok"}; !slices.Equal(reasons, want) {
+		t.Errorf("HostRates[a.example] reasons = %v, want %v", reasons, want)
+	}
+	if !slices.ContainsFunc(res.HostRates["b.example"], func(rc ratelimit.RateChange) bool { return rc.Reason == "error" }) {
+		t.Errorf("HostRates[b.example] = %v, want an error", res.HostRates["b.example"])
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Normalize resolves href against base and returns it in a canonical
+// form, so that spellings of the same page compare equal: the scheme
+// and host are lower-cased, default ports and the fragment are
+// dropped, dot segments are resolved and a trailing slash is removed
+// from any path but the root. Only http and https URLs are accepted.
+func Normalize(base *url.URL, href string) (string, bool) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	u := ref
+	if base != nil {
+		u = base.ResolveReference(ref)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", false
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" || u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443" {
+		u.Host = host
+	} else {
+		u.Host = host + ":" + port
+	}
+	u.Fragment, u.RawFragment = "", ""
+	u.User = nil
+	if u.Path == "" {
+		u.Path = "/"
+	} else if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	u.RawPath = ""
+	return u.String(), true
+}
+
+// extractLinks returns the normalised targets of the <a href> tags in
+// the HTML document doc, in document order and without duplicates.
+// Relative links are resolved against base, or against the document's
+// <base href> if it has one. It is a small tag scanner rather than a
+// full HTML parser: it skips comments and the contents of script and
+// style elements, which is all link extraction needs.
+func extractLinks(base *url.URL, doc string) []string {
+	var (
+		links []string
+		seen  = map[string]bool{}
+	)
+	for i := 0; i < len(doc); {
+		lt := strings.IndexByte(doc[i:], '<')
+		if lt < 0 {
+			break
+		}
+		i += lt
+		if strings.HasPrefix(doc[i:], "<!--") {
+			end := strings.Index(doc[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+		name, attrs, n := parseTag(doc[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		i += n
+		switch name {
+		case "script", "style":
+			end := strings.Index(strings.ToLower(doc[i:]), "</"+name)
+			if end < 0 {
+				return links
+			}
+			i += end
+		case "base":
+			if href, ok := attrs["href"]; ok {
+				if u, err := url.Parse(href); err == nil {
+					if base != nil {
+						u = base.ResolveReference(u)
+					}
+					base = u
+				}
+			}
+		case "a":
+			href, ok := attrs["href"]
+			if !ok {
+				continue
+			}
+			if u, ok := Normalize(base, href); ok && !seen[u] {
+				seen[u] = true
+				links = append(links, u)
+			}
+		}
+	}
+	return links
+}
+
+// parseTag parses the start tag at the beginning of s and returns its
+// lower-cased name, its attributes and its length. It returns n == 0
+// if s does not start with a start tag.
+func parseTag(s string) (name string, attrs map[string]string, n int) {
+	i := 1
+	for i < len(s) && isNameByte(s[i]) {
+		i++
+	}
+	if i == 1 {
+		return "", nil, 0
+	}
+	name = strings.ToLower(s[1:i])
+	attrs = map[string]string{}
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return "", nil, 0
+		}
+		if s[i] == '>' {
+			return name, attrs, i + 1
+		}
+		if s[i] == '/' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		key := strings.ToLower(s[start:i])
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			attrs[key] = ""
+			continue
+		}
+		i++
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return "", nil, 0
+		}
+		var val string
+		if q := s[i]; q == '"' || q == '\'' {
+			end := strings.IndexByte(s[i+1:], q)
+			if end < 0 {
+				return "", nil, 0
+			}
+			val = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start := i
+			for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			val = s[start:i]
+		}
+		if _, dup := attrs[key]; !dup {
+			attrs[key] = html.UnescapeString(val)
+		}
+	}
+}
+
+func isNameByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+const usage = `usage: crawl [flags] url...
+
+Crawl fetches each url and the pages it links to, and prints every
+page as it is fetched, then a summary. Flags:
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(crawlCommand(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// crawlCommand runs the crawl command with args and returns its exit
+// status: 0 on success, 1 if the crawl failed and 2 for bad usage.
+func crawlCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("crawl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	var (
+		depth       = fs.Int("depth", 4, "`levels` of pages to fetch, counting the roots")
+		concurrency = fs.Int("concurrency", DefaultConcurrency, "pages fetched at once")
+		maxPages    = fs.Int("max-pages", 0, "stop after `n` pages, 0 for no limit")
+		rate        = fs.Float64("rate", 0, "requests per second per host, 0 for no limit")
+		burst       = fs.Int("burst", 1, "requests per host allowed in a burst")
+		adaptive    = fs.Bool("adaptive", false, "adapt each host's rate, between -min-rate and -rate, to how it responds")
+		minRate     = fs.Float64("min-rate", 0.5, "lowest rate per host with -adaptive")
+		order       = fs.String("order", "bfs", "fetch `order`: bfs, dfs or priority (shallow pages first)")
+		sameHost    = fs.Bool("same-host", false, "only follow links to the root's host")
+		sameDomain  = fs.Bool("same-domain", false, "only follow links within the root's domain")
+		maxPerHost  = fs.Int("max-per-host", 0, "queue at most `n` pages per host, 0 for no limit")
+		strip       = fs.String("strip-params", "", "comma-separated query `params` to drop from links, * for all")
+		format      = fs.String("format", "text", "output `format`: text, jsonl or dot")
+		fixture     = fs.String("fixture", "", "crawl the fake site described in this JSON `file` instead of the web")
+		userAgent   = fs.String("user-agent", "gopher-crawler", "User-Agent sent and matched against robots.txt")
+		robots      = fs.Bool("robots", true, "honour robots.txt")
+		include     = patternsFlag{glob: true}
+		exclude     patternsFlag
+	)
+	fs.Var(&include, "include", "only follow links matching this `glob`; repeatable")
+	fs.Var(&exclude, "exclude", "never follow links matching this `regexp`; repeatable")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	roots := fs.Args()
+	if len(roots) == 0 {
+		fs.Usage()
+		return 2
+	}
+	out, ok := outputs[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "crawl: unknown format %q\n", *format)
+		return 2
+	}
+
+	var (
+		f  ContextFetcher
+		hf *HTTPFetcher
+	)
+	if *fixture != "" {
+		site, err := loadFixture(*fixture)
+		if err != nil {
+			fmt.Fprintf(stderr, "crawl: %v\n", err)
+			return 1
+		}
+		f = WithContext(site)
+	} else {
+		hf = &HTTPFetcher{UserAgent: *userAgent}
+		f = WithContext(hf)
+	}
+	var limiter ratelimit.HostLimiter
+	switch {
+	case *adaptive && (*rate <= 0 || *minRate <= 0 || *minRate > *rate):
+		fmt.Fprintln(stderr, "crawl: -adaptive needs 0 < -min-rate <= -rate")
+		return 2
+	case *adaptive:
+		limiter = ratelimit.NewAdaptive(*minRate, *rate, ratelimit.SystemClock)
+	case *rate > 0:
+		interval := time.Duration(float64(time.Second) / *rate)
+		limiter = ratelimit.NewPerHost(interval, *burst, 0, ratelimit.SystemClock)
+	case *robots:
+		// No rate of its own: only Crawl-delay spaces requests.
+		limiter = ratelimit.NewPerHost(0, *burst, 0, ratelimit.SystemClock)
+	}
+	if *robots {
+		rf := &RobotsFetcher{Fetcher: f, UserAgent: *userAgent}
+		if ds, ok := limiter.(DelaySetter); ok {
+			rf.Limiter = ds
+		}
+		if hf != nil {
+			hf.Client = &http.Client{CheckRedirect: rf.CheckRedirect}
+		}
+		f = rf
+	}
+
+	c := NewCrawler(f)
+	c.Concurrency = *concurrency
+	c.Limiter = limiter
+	switch *order {
+	case "bfs":
+		c.Order = BFS
+	case "dfs":
+		c.Order = DFS
+	case "priority":
+		c.Order = Priority
+	default:
+		fmt.Fprintf(stderr, "crawl: unknown order %q\n", *order)
+		return 2
+	}
+	c.Policy = Policy{
+		SameHost:        *sameHost,
+		SameDomain:      *sameDomain,
+		Include:         include.res,
+		Exclude:         exclude.res,
+		MaxPagesPerHost: *maxPerHost,
+	}
+	if *strip != "" {
+		c.Policy.StripParams = strings.Split(*strip, ",")
+	}
+	if out.page != nil {
+		c.OnPage = func(p Page) error {
+			return out.page(stdout, &p)
+		}
+	}
+
+	all := &CrawlResult{}
+	start := time.Now()
+	var err error
+	for _, root := range roots {
+		// -max-pages caps the whole crawl, not each root.
+		if *maxPages > 0 {
+			if c.MaxPages = *maxPages - len(all.Pages); c.MaxPages <= 0 {
+				break
+			}
+		}
+		var res *CrawlResult
+		res, err = c.Run(ctx, root, *depth)
+		for _, p := range res.Pages {
+			all.add(p)
+		}
+		all.Filtered = append(all.Filtered, res.Filtered...)
+		all.HostRates = res.HostRates
+		if err != nil {
+			break
+		}
+	}
+	if out.end != nil {
+		err = errors.Join(err, out.end(stdout, all))
+	}
+	fmt.Fprintf(stderr, "crawled %d pages, %d errors, %d filtered links in %v\n",
+		len(all.Pages), len(all.Errors()), len(all.Filtered), time.Since(start).Round(time.Microsecond))
+	for _, host := range slices.Sorted(maps.Keys(all.HostRates)) {
+		rates := all.HostRates[host]
+		fmt.Fprintf(stderr, "%s: %.2g requests/s after %d rate changes\n", host, rates[len(rates)-1].Rate, len(rates)-1)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "crawl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// output writes each page as it is fetched, if page is set,
+// and the whole crawl once it is over, if end is set.
+type output struct {
+	page func(w io.Writer, p *Page) error
+	end  func(w io.Writer, res *CrawlResult) error
+}
+
+var outputs = map[string]output{
+	"text": {page: func(w io.Writer, p *Page) error {
+		if p.Err != nil {
+			_, err := fmt.Fprintf(w, "%d\t%s\terror: %v\n", p.Depth, p.URL, p.Err)
+			return err
+		}
+		_, err := fmt.Fprintf(w, "%d\t%s\t%d links\n", p.Depth, p.URL, len(p.Links))
+		return err
+	}},
+	"jsonl": {page: func(w io.Writer, p *Page) error {
+		return json.NewEncoder(w).Encode(p)
+	}},
+	"dot": {end: func(w io.Writer, res *CrawlResult) error {
+		return res.WriteDOT(w)
+	}},
+}
+
+// patternsFlag collects the patterns of a repeated flag,
+// taking them as globs if glob is set and as regexps otherwise.
+type patternsFlag struct {
+	glob bool
+	raw  []string
+	res  []*regexp.Regexp
+}
+
+func (f *patternsFlag) String() string { return strings.Join(f.raw, " ") }
+
+func (f *patternsFlag) Set(s string) error {
+	var re *regexp.Regexp
+	if f.glob {
+		re = Glob(s)
+	} else {
+		var err error
+		if re, err = regexp.Compile(s); err != nil {
+			return err
+		}
+	}
+	f.raw = append(f.raw, s)
+	f.res = append(f.res, re)
+	return nil
+}
+
+// fixtureSite is a fake site read from a JSON file that maps
+// each URL to its page:
+//
+//	{
+//		"https://golang.org/": {
+//			"body": "The Go Programming Language",
+//			"links": ["https://golang.org/pkg/"]
+//		},
+//		"https://golang.org/pkg/": {"error": "server on fire"}
+//	}
+//
+// URLs missing from the file are not found.
+type fixtureSite map[string]fixturePage
+
+type fixturePage struct {
+	Body  string   `json:"body"`
+	Links []string `json:"links"`
+	Err   string   `json:"error"`
+}
+
+func loadFixture(path string) (fixtureSite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var site fixtureSite
+	if err := json.Unmarshal(data, &site); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return site, nil
+}
+
+func (s fixtureSite) Fetch(url string) (string, []string, error) {
+	p, ok := s[url]
+	if !ok {
+		return "", nil, fmt.Errorf("not found: %s", url)
+	}
+	if p.Err != "" {
+		return "", nil, errors.New(p.Err)
+	}
+	return p.Body, p.Links, nil
+}
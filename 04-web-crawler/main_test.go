@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCrawlCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		status  int
+		stdout  string
+		summary string
+	}{
+		{
+			name: "text",
+			args: []string{"-concurrency", "1", "https://golang.org/"},
+			stdout: "0\thttps://golang.org/\t2 links\n" +
+				"1\thttps://golang.org/pkg/\t4 links\n" +
+				"1\thttps://golang.org/cmd/\terror: not found: https://golang.org/cmd/\n" +
+				"2\thttps://golang.org/pkg/fmt/\t2 links\n" +
+				"2\thttps://golang.org/pkg/os/\t2 links\n",
+			summary: "crawled 5 pages, 1 errors, 0 filtered links",
+		},
+		{
+			name: "depth and dfs",
+			args: []string{"-concurrency", "1", "-depth", "3", "-order", "dfs", "https://golang.org/"},
+			stdout: "0\thttps://golang.org/\t2 links\n" +
+				"1\thttps://golang.org/pkg/\t4 links\n" +
+				"2\thttps://golang.org/pkg/fmt/\t2 links\n" +
+				"2\thttps://golang.org/pkg/os/\t2 links\n" +
+				"1\thttps://golang.org/cmd/\terror: not found: https://golang.org/cmd/\n",
+			summary: "crawled 5 pages, 1 errors, 0 filtered links",
+		},
+		{
+			name: "scope rules",
+			args: []string{"-concurrency", "1", "-include", "https://golang.org/pkg/*", "-exclude", "os/$", "https://golang.org/"},
+			stdout: "0\thttps://golang.org/\t2 links\n" +
+				"1\thttps://golang.org/pkg/\t4 links\n" +
+				"2\thttps://golang.org/pkg/fmt/\t2 links\n",
+			summary: "crawled 3 pages, 0 errors, 2 filtered links",
+		},
+		{
+			name: "several roots",
+			args: []string{"-concurrency", "1", "-depth", "1", "https://golang.org/pkg/os/", "https://golang.org/pkg/fmt/"},
+			stdout: "0\thttps://golang.org/pkg/os/\t2 links\n" +
+				"0\thttps://golang.org/pkg/fmt/\t2 links\n",
+			summary: "crawled 2 pages, 0 errors, 0 filtered links",
+		},
+		{
+			name:    "max pages across roots",
+			args:    []string{"-concurrency", "1", "-depth", "1", "-max-pages", "1", "https://golang.org/pkg/os/", "https://golang.org/pkg/fmt/"},
+			stdout:  "0\thttps://golang.org/pkg/os/\t2 links\n",
+			summary: "crawled 1 pages, 0 errors, 0 filtered links",
+		},
+		{
+			name: "dot",
+			args: []string{"-format", "dot", "-depth", "1", "https://golang.org/"},
+			stdout: "digraph crawl {\n" +
+				"\t\"https://golang.org/\";\n" +
+				"\t\"https://golang.org/\" -> \"https://golang.org/pkg/\" [style=dashed];\n" +
+				"\t\"https://golang.org/\" -> \"https://golang.org/cmd/\" [style=dashed];\n" +
+				"}\n",
+			summary: "crawled 1 pages, 0 errors, 0 filtered links",
+		},
+		{
+			name:   "no roots",
+			status: 2,
+		},
+		{
+			name:   "bad format",
+			args:   []string{"-format", "xml", "https://golang.org/"},
+			status: 2,
+		},
+		{
+			name:   "bad order",
+			args:   []string{"-order", "random", "https://golang.org/"},
+			status: 2,
+		},
+		{
+			name:   "bad exclude",
+			args:   []string{"-exclude", "(", "https://golang.org/"},
+			status: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-fixture", "testdata/golang.json"}, tt.args...)
+			status := crawlCommand(context.Background(), args, &stdout, &stderr)
+			if status != tt.status {
+				t.Fatalf("crawlCommand() status = %d, want %d; stderr:\n%s", status, tt.status, stderr.String())
+			}
+			if status != 0 {
+				return
+			}
+			if got := stdout.String(); got != tt.stdout {
+				t.Errorf("crawlCommand() stdout =\n%s\nwant\n%s", got, tt.stdout)
+			}
+			if got := stderr.String(); !strings.HasPrefix(got, tt.summary+" in ") {
+				t.Errorf("crawlCommand() summary = %q, want %q", got, tt.summary)
+			}
+		})
+	}
+}
+
+func TestCrawlCommandJSONLines(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-fixture", "testdata/golang.json", "-format", "jsonl", "https://golang.org/"}
+	if status := crawlCommand(context.Background(), args, &stdout, &stderr); status != 0 {
+		t.Fatalf("crawlCommand() status = %d; stderr:\n%s", status, stderr.String())
+	}
+	var urls []string
+	for line := range strings.Lines(stdout.String()) {
+		var p Page
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			t.Fatalf("bad JSON line %q: %v", line, err)
+		}
+		urls = append(urls, p.URL)
+	}
+	sort.Strings(urls)
+	want := "https://golang.org/ https://golang.org/cmd/ https://golang.org/pkg/ https://golang.org/pkg/fmt/ https://golang.org/pkg/os/"
+	if got := strings.Join(urls, " "); got != want {
+		t.Errorf("crawlCommand() pages = %s, want %s", got, want)
+	}
+	if !regexp.MustCompile(`^crawled 5 pages, 1 errors, 0 filtered links in \S+\n$`).MatchString(stderr.String()) {
+		t.Errorf("crawlCommand() summary = %q", stderr.String())
+	}
+}
+
+func TestCrawlCommandMissingFixture(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-fixture", "testdata/missing.json", "https://golang.org/"}
+	if status := crawlCommand(context.Background(), args, &stdout, &stderr); status != 1 {
+		t.Errorf("crawlCommand() status = %d, want 1", status)
+	}
+}
+
+func TestCrawlCommandCrawlDelay(t *testing.T) {
+	// Without -rate, robots.txt alone spaces the requests.
+	fixture := filepath.Join(t.TempDir(), "site.json")
+	site := `{
+		"https://x.example/robots.txt": {"body": "User-agent: *\nCrawl-delay: 0.2\n"},
+		"https://x.example/": {"body": "root", "links": ["https://x.example/a", "https://x.example/b"]},
+		"https://x.example/a": {"body": "a"},
+		"https://x.example/b": {"body": "b"}
+	}`
+	if err := os.WriteFile(fixture, []byte(site), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	start := time.Now()
+	args := []string{"-fixture", fixture, "https://x.example/"}
+	if status := crawlCommand(context.Background(), args, &stdout, &stderr); status != 0 {
+		t.Fatalf("crawlCommand() status = %d; stderr:\n%s", status, stderr.String())
+	}
+	// Three pages. Crawl-delay is set before the root is fetched,
+	// on a bucket that starts again empty: each page waits 0.2s.
+	if d := time.Since(start); d < 600*time.Millisecond {
+		t.Errorf("crawlCommand() took %v, want at least 600ms", d)
+	}
+}
+
+func TestCrawlCommandCrawlDelaySpacing(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"with rate", []string{"-rate", "100"}},
+		{"without rate", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The root links to four pages on a slow host, so four
+			// workers reach it at once. The slow host times the
+			// requests: each must wait for robots.txt, then for its
+			// turn under the Crawl-delay.
+			var (
+				mu    sync.Mutex
+				times []time.Time
+			)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(50 * time.Millisecond)
+				w.Write([]byte("User-agent: *\nCrawl-delay: 0.2\n"))
+			})
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				times = append(times, time.Now())
+				mu.Unlock()
+				w.Write([]byte("page"))
+			})
+			slow := httptest.NewServer(mux)
+			defer slow.Close()
+			root := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html")
+				for i := range 4 {
+					fmt.Fprintf(w, `<a href="%s/p%d">p</a>`, slow.URL, i)
+				}
+			}))
+			defer root.Close()
+
+			var stdout, stderr bytes.Buffer
+			args := append(tt.args, "-concurrency", "4", "-depth", "2", root.URL+"/")
+			if status := crawlCommand(context.Background(), args, &stdout, &stderr); status != 0 {
+				t.Fatalf("crawlCommand() status = %d; stderr:\n%s", status, stderr.String())
+			}
+			if len(times) != 4 {
+				t.Fatalf("slow host got %d page requests, want 4", len(times))
+			}
+			for i := 1; i < len(times); i++ {
+				if d := times[i].Sub(times[i-1]); d < 180*time.Millisecond {
+					t.Errorf("request %d came %v after the one before, want 200ms", i, d)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Policy decides which links a crawl follows. The zero Policy
+// follows every link. The root URL is always fetched.
+type Policy struct {
+	// SameHost only follows links to the root's host.
+	SameHost bool
+	// SameDomain only follows links within the root's domain: its
+	// registered domain, one label below a public suffix, so that
+	// go.dev also admits pkg.go.dev but bbc.co.uk does not admit
+	// gov.co.uk.
+	SameDomain bool
+	// Include, if not empty, only follows links matching one of its
+	// patterns. Exclude never follows links matching one of its
+	// patterns. Glob builds patterns from shell-style globs.
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+	// MaxPagesPerHost stops queueing links to a host once that many
+	// of its URLs are queued. It counts URLs queued, not fetched:
+	// the root and pages that fail to fetch count too, and so do
+	// pages never fetched because MaxPages or a cancel stopped the
+	// crawl first. Zero means no limit.
+	MaxPagesPerHost int
+	// StripParams lists query parameters removed from every link
+	// before it is queued; "*" removes the whole query.
+	StripParams []string
+}
+
+// Filtered is a link a Policy did not follow.
+type Filtered struct {
+	URL  string `json:"url"`
+	From string `json:"from"`
+	// Rule names the rule that rejected URL: "same-host",
+	// "same-domain", "include", "exclude <pattern>" or
+	// "max-pages-per-host".
+	Rule string `json:"rule"`
+}
+
+// Glob compiles a shell-style glob into a pattern for Policy:
+// * matches any run of characters and ? matches any one character.
+// The glob must match the whole URL.
+func Glob(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// rewrite applies StripParams to rawURL.
+func (p *Policy) rewrite(rawURL string) string {
+	if len(p.StripParams) == 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	q := u.Query()
+	for _, name := range p.StripParams {
+		if name == "*" {
+			q = nil
+			break
+		}
+		q.Del(name)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// reject returns the rule that keeps a crawl started at root from
+// following rawURL, or "" if the link may be followed. perHost counts
+// the pages queued so far for each host.
+func (p *Policy) reject(root, rawURL string, perHost map[string]int) string {
+	host := hostname(rawURL)
+	switch {
+	case p.SameHost && host != hostname(root):
+		return "same-host"
+	case p.SameDomain && domain(host) != domain(hostname(root)):
+		return "same-domain"
+	case len(p.Include) > 0 && !matchAny(p.Include, rawURL):
+		return "include"
+	}
+	for _, re := range p.Exclude {
+		if re.MatchString(rawURL) {
+			return "exclude " + re.String()
+		}
+	}
+	if p.MaxPagesPerHost > 0 && perHost[host] >= p.MaxPagesPerHost {
+		return "max-pages-per-host"
+	}
+	return ""
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostname returns the lower-cased host name of rawURL, without port.
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// domain returns the registered domain of host, or host itself if
+// it has none, like an IP address or a public suffix.
+func domain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	d, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return d
+}
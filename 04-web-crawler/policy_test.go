@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// policySite spans three hosts in two domains.
+var policySite = fakeFetcher{
+	"https://go.dev/": &fakeResult{
+		"Go",
+		[]string{
+			"https://go.dev/doc/",
+			"https://go.dev/blog/?utm_source=feed&id=1",
+			"https://go.dev/doc/spec.pdf",
+			"https://pkg.go.dev/fmt",
+			"https://example.com/",
+		},
+	},
+	"https://go.dev/doc/":                       &fakeResult{"Docs", []string{"https://go.dev/"}},
+	"https://go.dev/blog/?id=1":                 &fakeResult{"Blog 1", nil},
+	"https://go.dev/blog/?utm_source=feed&id=1": &fakeResult{"Blog 1 from feed", nil},
+	"https://go.dev/blog/":                      &fakeResult{"Blog", nil},
+	"https://go.dev/doc/spec.pdf":               &fakeResult{"Spec", nil},
+	"https://pkg.go.dev/fmt":                    &fakeResult{"Package fmt", nil},
+	"https://example.com/":                      &fakeResult{"Example", nil},
+}
+
+func TestCrawlerPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		bodies   []string
+		filtered map[string]string
+	}{
+		{
+			name:   "zero policy follows everything",
+			bodies: []string{"Blog 1 from feed", "Docs", "Example", "Go", "Package fmt", "Spec"},
+		},
+		{
+			name:   "same host",
+			policy: Policy{SameHost: true},
+			bodies: []string{"Blog 1 from feed", "Docs", "Go", "Spec"},
+			filtered: map[string]string{
+				"https://pkg.go.dev/fmt": "same-host",
+				"https://example.com/":   "same-host",
+			},
+		},
+		{
+			name:   "same domain",
+			policy: Policy{SameDomain: true},
+			bodies: []string{"Blog 1 from feed", "Docs", "Go", "Package fmt", "Spec"},
+			filtered: map[string]string{
+				"https://example.com/": "same-domain",
+			},
+		},
+		{
+			name:   "include glob",
+			policy: Policy{Include: []*regexp.Regexp{Glob("https://go.dev/doc/*")}},
+			bodies: []string{"Docs", "Go", "Spec"},
+			filtered: map[string]string{
+				"https://go.dev/blog/?utm_source=feed&id=1": "include",
+				"https://pkg.go.dev/fmt":                    "include",
+				"https://example.com/":                      "include",
+			},
+		},
+		{
+			name:   "exclude regexp",
+			policy: Policy{SameHost: true, Exclude: []*regexp.Regexp{regexp.MustCompile(`\.pdf$`)}},
+			bodies: []string{"Blog 1 from feed", "Docs", "Go"},
+			filtered: map[string]string{
+				"https://go.dev/doc/spec.pdf": `exclude \.pdf$`,
+				"https://pkg.go.dev/fmt":      "same-host",
+				"https://example.com/":        "same-host",
+			},
+		},
+		{
+			name:   "strip one param",
+			policy: Policy{SameHost: true, StripParams: []string{"utm_source"}},
+			bodies: []string{"Blog 1", "Docs", "Go", "Spec"},
+			filtered: map[string]string{
+				"https://pkg.go.dev/fmt": "same-host",
+				"https://example.com/":   "same-host",
+			},
+		},
+		{
+			name:   "strip whole query",
+			policy: Policy{SameHost: true, StripParams: []string{"*"}},
+			bodies: []string{"Blog", "Docs", "Go", "Spec"},
+			filtered: map[string]string{
+				"https://pkg.go.dev/fmt": "same-host",
+				"https://example.com/":   "same-host",
+			},
+		},
+		{
+			name:   "max pages per host",
+			policy: Policy{MaxPagesPerHost: 2},
+			bodies: []string{"Docs", "Example", "Go", "Package fmt"},
+			filtered: map[string]string{
+				"https://go.dev/blog/?utm_source=feed&id=1": "max-pages-per-host",
+				"https://go.dev/doc/spec.pdf":               "max-pages-per-host",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCrawler(WithContext(policySite))
+			c.Concurrency = 1
+			c.Policy = tt.policy
+
+			res, err := c.Run(context.Background(), "https://go.dev/", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bodies := res.Bodies()
+			sort.Strings(bodies)
+			if !reflect.DeepEqual(bodies, tt.bodies) {
+				t.Errorf("Run() bodies = %q, want %q", bodies, tt.bodies)
+			}
+			filtered := map[string]string{}
+			for _, f := range res.Filtered {
+				filtered[f.URL] = f.Rule
+				if f.From != "https://go.dev/" {
+					t.Errorf("Filtered %s from %s, want from the root", f.URL, f.From)
+				}
+			}
+			if len(filtered) == 0 {
+				filtered = nil
+			}
+			if !reflect.DeepEqual(filtered, tt.filtered) {
+				t.Errorf("Run() filtered = %v, want %v", filtered, tt.filtered)
+			}
+		})
+	}
+}
+
+func TestDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"go.dev", "go.dev"},
+		{"pkg.go.dev", "go.dev"},
+		{"www.bbc.co.uk", "bbc.co.uk"},
+		{"gov.co.uk", "gov.co.uk"},
+		{"a.b.example.com.au", "example.com.au"},
+		{"co.uk", "co.uk"},
+		{"localhost", "localhost"},
+		{"127.0.0.1", "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := domain(tt.host); got != tt.want {
+				t.Errorf("domain() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if domain("www.bbc.co.uk") == domain("www.gov.co.uk") {
+		t.Errorf("bbc.co.uk and gov.co.uk share a domain")
+	}
+}
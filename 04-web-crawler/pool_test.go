@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"concurrency/fetchertest"
+)
+
+// wideSite returns a fakeFetcher whose root links to n pages,
+// each of which links back to the root and to its neighbour.
+func wideSite(n int) fakeFetcher {
+	f := fakeFetcher{}
+	root := &fakeResult{body: "root"}
+	f["root"] = root
+	for i := range n {
+		u := fmt.Sprintf("page/%d", i)
+		root.urls = append(root.urls, u)
+		f[u] = &fakeResult{
+			body: u,
+			urls: []string{"root", fmt.Sprintf("page/%d", (i+1)%n)},
+		}
+	}
+	return f
+}
+
+func TestCrawlerConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		maxPages    int
+		want        int
+	}{
+		{"one worker", 1, 0, 201},
+		{"four workers", 4, 0, 201},
+		{"default workers", 0, 0, 201},
+		{"max pages", 4, 50, 50},
+		{"max pages above site size", 4, 1000, 201},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fetchertest.RecordingFetcher{Fetcher: wideSite(200), Delay: time.Millisecond}
+			c := NewCrawler(WithContext(f))
+			c.Concurrency = tt.concurrency
+			c.MaxPages = tt.maxPages
+
+			res, err := c.Run(context.Background(), "root", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Pages) != tt.want {
+				t.Errorf("Run() fetched %d pages, want %d", len(res.Pages), tt.want)
+			}
+			limit := tt.concurrency
+			if limit == 0 {
+				limit = DefaultConcurrency
+			}
+			fetchertest.AssertMaxParallelism(t, f.Fetches(), limit)
+			fetchertest.AssertFetchedOnce(t, f.Fetches())
+		})
+	}
+}
+
+func TestCrawlerRunAfterStop(t *testing.T) {
+	const n = 100
+	errStop := errors.New("stop")
+	for range 20 {
+		site := wideSite(n)
+		site["entry"] = &fakeResult{body: "entry", urls: site["root"].urls}
+		f := &fetchertest.RecordingFetcher{Fetcher: site}
+		c := NewCrawler(WithContext(f))
+		c.Concurrency = 8
+		pages := 0
+		c.OnPage = func(Page) error {
+			if pages++; pages == 10 {
+				return errStop
+			}
+			return nil
+		}
+		if _, err := c.Run(context.Background(), "root", 2); err != errStop {
+			t.Fatalf("Run() error = %v, want %v", err, errStop)
+		}
+
+		// A second crawl on the same Crawler fetches what
+		// the first one left, and nothing twice.
+		c.OnPage = nil
+		if _, err := c.Run(context.Background(), "entry", 2); err != nil {
+			t.Fatal(err)
+		}
+		fetchertest.AssertFetchedOnce(t, f.Fetches())
+		if got := len(f.Fetches()); got != n+2 {
+			t.Fatalf("Run() twice fetched %d pages, want %d", got, n+2)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+// Page is the outcome of fetching one URL.
+type Page struct {
+	URL      string
+	Body     string
+	Depth    int    // distance from the root, which is at depth 0
+	Parent   string // page the URL was found on, empty for the root
+	Links    []string
+	Err      error
+	Duration time.Duration
+	// DuplicateOf is the URL of the earlier page this page's body
+	// duplicates, if the crawl looked for duplicates.
+	DuplicateOf string
+
+	fingerprint *fingerprint
+}
+
+// Edge is a link From one page To another.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// CrawlResult holds one Page per fetched URL, in the order the
+// fetches finished, every link found on them, leaving out those of
+// duplicates, and the links the crawl's Policy did not follow.
+type CrawlResult struct {
+	Pages    []*Page
+	Edges    []Edge
+	Filtered []Filtered
+	// HostRates is the rate history of every host, if the crawl's
+	// Limiter keeps one.
+	HostRates map[string][]ratelimit.RateChange
+
+	index map[string]*Page
+}
+
+func (r *CrawlResult) add(p *Page) {
+	if r.index == nil {
+		r.index = make(map[string]*Page)
+	}
+	r.index[p.URL] = p
+	r.Pages = append(r.Pages, p)
+	if p.Err != nil || p.DuplicateOf != "" {
+		return
+	}
+	for _, u := range p.Links {
+		r.Edges = append(r.Edges, Edge{From: p.URL, To: u})
+	}
+}
+
+// Page returns the page fetched for url, or nil.
+func (r *CrawlResult) Page(url string) *Page {
+	return r.index[url]
+}
+
+// Bodies returns the bodies of the pages fetched without error,
+// leaving out duplicates.
+func (r *CrawlResult) Bodies() []string {
+	var bodies []string
+	for _, p := range r.Pages {
+		if p.Err == nil && p.DuplicateOf == "" {
+			bodies = append(bodies, p.Body)
+		}
+	}
+	return bodies
+}
+
+// Duplicates groups the URLs of pages with the same or nearly the same
+// body. Each group starts with the original page, followed by its
+// duplicates in the order they were fetched.
+func (r *CrawlResult) Duplicates() [][]string {
+	var groups [][]string
+	group := make(map[string]int)
+	for _, p := range r.Pages {
+		if p.DuplicateOf == "" {
+			continue
+		}
+		i, ok := group[p.DuplicateOf]
+		if !ok {
+			i = len(groups)
+			group[p.DuplicateOf] = i
+			groups = append(groups, []string{p.DuplicateOf})
+		}
+		groups[i] = append(groups[i], p.URL)
+	}
+	return groups
+}
+
+// Errors returns the fetch error of every failed page, by URL.
+func (r *CrawlResult) Errors() map[string]error {
+	errs := make(map[string]error)
+	for _, p := range r.Pages {
+		if p.Err != nil {
+			errs[p.URL] = p.Err
+		}
+	}
+	return errs
+}
+
+// WriteDOT writes the crawl as a Graphviz digraph. Failed pages are
+// drawn in red and links to pages that were not fetched are dashed.
+func (r *CrawlResult) WriteDOT(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("digraph crawl {\n")
+	for _, p := range r.Pages {
+		if p.Err != nil {
+			ew.printf("\t%s [color=red, tooltip=%s];\n", strconv.Quote(p.URL), strconv.Quote(p.Err.Error()))
+		} else {
+			ew.printf("\t%s;\n", strconv.Quote(p.URL))
+		}
+	}
+	for _, e := range r.Edges {
+		if r.Page(e.To) == nil {
+			ew.printf("\t%s -> %s [style=dashed];\n", strconv.Quote(e.From), strconv.Quote(e.To))
+		} else {
+			ew.printf("\t%s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+		}
+	}
+	ew.printf("}\n")
+	return ew.err
+}
+
+// WriteJSON writes the crawl as an indented JSON document.
+func (r *CrawlResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *CrawlResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Pages     []*Page                           `json:"pages"`
+		Edges     []Edge                            `json:"edges"`
+		Filtered  []Filtered                        `json:"filtered,omitempty"`
+		HostRates map[string][]ratelimit.RateChange `json:"host_rates,omitempty"`
+	}{r.Pages, r.Edges, r.Filtered, r.HostRates})
+}
+
+// jsonPage is the JSON form of a Page.
+type jsonPage struct {
+	URL      string   `json:"url"`
+	Body     string   `json:"body,omitempty"`
+	Depth    int      `json:"depth"`
+	Parent   string   `json:"parent,omitempty"`
+	Links    []string `json:"links,omitempty"`
+	Err      string   `json:"error,omitempty"`
+	Duration string   `json:"duration"`
+
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+func (p *Page) MarshalJSON() ([]byte, error) {
+	jp := jsonPage{
+		URL:      p.URL,
+		Body:     p.Body,
+		Depth:    p.Depth,
+		Parent:   p.Parent,
+		Links:    p.Links,
+		Duration: p.Duration.String(),
+
+		DuplicateOf: p.DuplicateOf,
+	}
+	if p.Err != nil {
+		jp.Err = p.Err.Error()
+	}
+	return json.Marshal(jp)
+}
+
+// UnmarshalJSON reads a Page written by MarshalJSON. The fetch error
+// comes back as a plain error with the same message.
+func (p *Page) UnmarshalJSON(data []byte) error {
+	var jp jsonPage
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(jp.Duration)
+	if err != nil {
+		return err
+	}
+	*p = Page{
+		URL:      jp.URL,
+		Body:     jp.Body,
+		Depth:    jp.Depth,
+		Parent:   jp.Parent,
+		Links:    jp.Links,
+		Duration: d,
+
+		DuplicateOf: jp.DuplicateOf,
+	}
+	if jp.Err != "" {
+		p.Err = errors.New(jp.Err)
+	}
+	return nil
+}
+
+// errWriter keeps the first write error so a run of
+// printf calls can be checked once at the end.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	res, err := NewCrawler(WithContext(fetcher)).Run(context.Background(), "https://golang.org/", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url    string
+		depth  int
+		parent string
+		err    bool
+	}{
+		{"https://golang.org/", 0, "", false},
+		{"https://golang.org/pkg/", 1, "https://golang.org/", false},
+		// cmd is linked from the root and from pkg, either may claim it.
+		{"https://golang.org/cmd/", -1, "", true},
+		{"https://golang.org/pkg/fmt/", 2, "https://golang.org/pkg/", false},
+		{"https://golang.org/pkg/os/", 2, "https://golang.org/pkg/", false},
+	}
+	if len(res.Pages) != len(tests) {
+		t.Errorf("Run() got %d pages, want %d", len(res.Pages), len(tests))
+	}
+	for _, tt := range tests {
+		p := res.Page(tt.url)
+		if p == nil {
+			t.Errorf("Run() missing page %s", tt.url)
+			continue
+		}
+		if (p.Err != nil) != tt.err || tt.depth >= 0 && (p.Depth != tt.depth || p.Parent != tt.parent) {
+			t.Errorf("Page(%s) = depth %d parent %q err %v, want depth %d parent %q err %v",
+				tt.url, p.Depth, p.Parent, p.Err, tt.depth, tt.parent, tt.err)
+		}
+	}
+	if errs := res.Errors(); len(errs) != 1 || errs["https://golang.org/cmd/"] == nil {
+		t.Errorf("Errors() = %v, want only https://golang.org/cmd/", errs)
+	}
+	// Every link on the four fetched pages, duplicates included.
+	if len(res.Edges) != 10 {
+		t.Errorf("Run() got %d edges, want 10", len(res.Edges))
+	}
+}
+
+// result is a small crawl with one failed page.
+func result() *CrawlResult {
+	res := &CrawlResult{}
+	res.add(&Page{URL: "a", Body: "A", Links: []string{"b", "c"}})
+	res.add(&Page{URL: "b", Depth: 1, Parent: "a", Err: errors.New("not found: b")})
+	return res
+}
+
+func TestWriteDOT(t *testing.T) {
+	var sb strings.Builder
+	if err := result().WriteDOT(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph crawl {
+	"a";
+	"b" [color=red, tooltip="not found: b"];
+	"a" -> "b";
+	"a" -> "c" [style=dashed];
+}
+`
+	if got := sb.String(); got != want {
+		t.Errorf("WriteDOT() got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var sb strings.Builder
+	if err := result().WriteJSON(&sb); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Pages []map[string]any `json:"pages"`
+		Edges []Edge           `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(sb.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Pages) != 2 || got.Pages[0]["body"] != "A" || got.Pages[1]["error"] != "not found: b" {
+		t.Errorf("WriteJSON() pages = %v", got.Pages)
+	}
+	want := []Edge{{"a", "b"}, {"a", "c"}}
+	if !reflect.DeepEqual(got.Edges, want) {
+		t.Errorf("WriteJSON() edges = %v, want %v", got.Edges, want)
+	}
+}
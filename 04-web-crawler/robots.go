@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+// ErrDisallowed is returned for URLs that robots.txt forbids.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// Robots holds the rules of a robots.txt that apply to one user agent.
+// A nil *Robots allows everything.
+type Robots struct {
+	rules []robotsRule
+	// CrawlDelay is the group's Crawl-delay, zero if it has none.
+	CrawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// ParseRobots parses the robots.txt in data and keeps the group for
+// userAgent: the group naming the longest product token contained in
+// userAgent (case-insensitively), or else the "*" group. Groups naming
+// the same agent are merged. Lines it does not understand are ignored.
+func ParseRobots(data, userAgent string) *Robots {
+	type group struct {
+		agents []string
+		robots Robots
+	}
+	var (
+		groups  []*group
+		cur     *group
+		inRules bool // cur has seen a rule, so a new User-agent starts a new group
+	)
+	sc := bufio.NewScanner(strings.NewReader(data))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, val = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(val)
+		switch key {
+		case "user-agent":
+			if cur == nil || inRules {
+				cur = &group{}
+				groups = append(groups, cur)
+				inRules = false
+			}
+			cur.agents = append(cur.agents, strings.ToLower(val))
+		case "allow", "disallow":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if val == "" {
+				continue // an empty Disallow allows everything
+			}
+			cur.robots.rules = append(cur.robots.rules, robotsRule{allow: key == "allow", pattern: val})
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if secs, err := strconv.ParseFloat(val, 64); err == nil && secs > 0 {
+				cur.robots.CrawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	ua := strings.ToLower(userAgent)
+	best := -1 // length of the best matching token, 0 for "*"
+	res := &Robots{}
+	for _, g := range groups {
+		for _, a := range g.agents {
+			n := -1
+			if a == "*" {
+				n = 0
+			} else if a != "" && strings.Contains(ua, a) {
+				n = len(a)
+			}
+			if n < 0 || n < best {
+				continue
+			}
+			if n > best {
+				best, res = n, &Robots{}
+			}
+			res.rules = append(res.rules, g.robots.rules...)
+			res.CrawlDelay = max(res.CrawlDelay, g.robots.CrawlDelay)
+			break
+		}
+	}
+	return res
+}
+
+// Allowed reports whether path (with its query, if any) may be
+// fetched. The longest matching rule wins and Allow wins a tie;
+// a path no rule matches is allowed.
+func (r *Robots) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	if path == "" {
+		path = "/"
+	}
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !matchRobots(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > longest || n == longest && rule.allow {
+			allowed, longest = rule.allow, n
+		}
+	}
+	return allowed
+}
+
+// matchRobots matches path against a robots.txt pattern, where *
+// matches any run of characters and a final $ anchors the end.
+func matchRobots(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	path = path[len(parts[0]):]
+	for i, p := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(path, p)
+		}
+		j := strings.Index(path, p)
+		if j < 0 {
+			return false
+		}
+		path = path[j+len(p):]
+	}
+	return !anchored || path == ""
+}
+
+// RobotsFetcher is a ContextFetcher that honours robots.txt. Before
+// the first fetch from a host it fetches that host's /robots.txt
+// through Fetcher and caches the rules for UserAgent; URLs they forbid
+// fail with ErrDisallowed. A missing robots.txt (or any other error
+// the server answers with) allows everything, while a server error or
+// an unreachable server forbids everything, as RFC 9309 asks.
+// robots.txt is read whatever its content type. URLs are matched as
+// they are, so a rule for "/dir/" does not cover "/dir", the form
+// Normalize gives links; set CheckRedirect on the HTTP client to catch
+// the server's redirect from one to the other.
+type RobotsFetcher struct {
+	Fetcher   ContextFetcher
+	UserAgent string
+	// Limiter, if set, is told each host's Crawl-delay. It should
+	// be the Crawler's Limiter, so that the delay is waited for.
+	Limiter DelaySetter
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// DelaySetter is a limiter that can space out the requests to a host,
+// like ratelimit.PerHost and ratelimit.Adaptive.
+type DelaySetter interface {
+	SetDelay(host string, d time.Duration)
+}
+
+type robotsEntry struct {
+	robots *Robots
+	err    error
+	ready  chan struct{} // closed when robots and err are set
+}
+
+func (f *RobotsFetcher) Fetch(ctx context.Context, rawURL string) (string, []string, error) {
+	if err := f.Check(ctx, rawURL); err != nil {
+		return "", nil, err
+	}
+	return f.Fetcher.Fetch(ctx, rawURL)
+}
+
+// Check loads the rules for rawURL's host, if they are not loaded yet,
+// and fails with ErrDisallowed if they forbid it. Crawler calls it
+// before taking its Limiter's slot for the fetch, so that the
+// Crawl-delay the rules set already spaces that fetch.
+func (f *RobotsFetcher) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	robots, err := f.robots(ctx, u)
+	if err != nil {
+		return err
+	}
+	if !robots.Allowed(u.RequestURI()) {
+		return fmt.Errorf("%s: %w", rawURL, ErrDisallowed)
+	}
+	return nil
+}
+
+// CheckRedirect is an http.Client CheckRedirect function that checks
+// every redirect target with Check, and stops after 10 redirects like
+// the default. Use it for the client of the HTTPFetcher that f wraps,
+// so that a link the rules allow cannot be redirected to a page they
+// forbid.
+func (f *RobotsFetcher) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return f.Check(req.Context(), req.URL.String())
+}
+
+// robots returns the rules for u's host, fetching them once per host.
+// Concurrent callers for the same host wait for the first one.
+func (f *RobotsFetcher) robots(ctx context.Context, u *url.URL) (*Robots, error) {
+	key := u.Scheme + "://" + u.Host
+	f.mu.Lock()
+	if f.hosts == nil {
+		f.hosts = make(map[string]*robotsEntry)
+	}
+	e := f.hosts[key]
+	if e == nil {
+		e = &robotsEntry{ready: make(chan struct{})}
+		f.hosts[key] = e
+		f.mu.Unlock()
+
+		e.robots, e.err = f.load(ctx, key, u.Host)
+		if e.err != nil {
+			// Cancelled: let the next caller try again.
+			f.mu.Lock()
+			delete(f.hosts, key)
+			f.mu.Unlock()
+		}
+		close(e.ready)
+		return e.robots, e.err
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-e.ready:
+		return e.robots, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *RobotsFetcher) load(ctx context.Context, origin, host string) (*Robots, error) {
+	body, _, err := f.Fetcher.Fetch(ctx, origin+"/robots.txt")
+	var he *HTTPError
+	var ce *ContentTypeError
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.As(err, &ce):
+		// Served with the wrong type, but robots.txt all the same.
+		body = ce.Body
+	case errors.As(err, &he) && he.StatusCode >= 500, ratelimit.Unreachable(err):
+		return &Robots{rules: []robotsRule{{allow: false, pattern: "/"}}}, nil
+	case err != nil:
+		return nil, nil
+	}
+	robots := ParseRobots(body, f.UserAgent)
+	if f.Limiter != nil && robots.CrawlDelay > 0 {
+		f.Limiter.SetDelay(host, robots.CrawlDelay)
+	}
+	return robots, nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+const robotsFixture = `
+# Everyone else
+User-agent: *
+Disallow: /private/
+Allow: /private/open
+Crawl-delay: 1
+
+User-agent: GopherBot
+User-agent: other-bot
+Disallow: /secret
+Disallow: /*.pdf$
+Allow: /secret/public
+Crawl-delay: 2.5
+
+User-agent: gopherbot-news
+Disallow: /
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{"star group disallow", "somebot", "/private/data", false},
+		{"star group allow wins longer", "somebot", "/private/open/x", true},
+		{"star group unmatched path", "somebot", "/secret", true},
+		{"named group replaces star", "GopherBot/1.0", "/private/data", true},
+		{"named group case-insensitive", "gopherbot/1.0", "/secret/file", false},
+		{"longest rule wins", "gopherbot/1.0", "/secret/public/page", true},
+		{"wildcard anchored", "gopherbot/1.0", "/docs/spec.pdf", false},
+		{"wildcard anchored no match", "gopherbot/1.0", "/docs/spec.pdf.html", true},
+		{"second agent of group", "other-bot", "/secret", false},
+		{"most specific agent wins", "gopherbot-news/2", "/anything", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ParseRobots(robotsFixture, tt.userAgent)
+			if got := r.Allowed(tt.path); got != tt.want {
+				t.Errorf("Allowed(%q) for %q = %v, want %v", tt.path, tt.userAgent, got, tt.want)
+			}
+		})
+	}
+
+	if d := ParseRobots(robotsFixture, "GopherBot").CrawlDelay; d != 2500*time.Millisecond {
+		t.Errorf("CrawlDelay = %v, want 2.5s", d)
+	}
+	if d := ParseRobots(robotsFixture, "somebot").CrawlDelay; d != time.Second {
+		t.Errorf("CrawlDelay = %v, want 1s", d)
+	}
+	if !ParseRobots("", "GopherBot").Allowed("/") {
+		t.Error("empty robots.txt disallows /")
+	}
+}
+
+func TestMatchRobots(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/anything", true},
+		{"/a", "/abc", true},
+		{"/a$", "/abc", false},
+		{"/a$", "/a", true},
+		{"/*/b", "/x/y/b", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x", false},
+		{"/a*c*e", "/abcde", true},
+		{"/a*c*e", "/abd", false},
+	}
+	for _, tt := range tests {
+		if got := matchRobots(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchRobots(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+// robotsSite serves robots.txt from robots, as contentType if set, or
+// answers with status if robots is empty, and an HTML page linking to
+// /private/ and /public/ everywhere else.
+func robotsSite(robots, contentType string, status int) (*httptest.Server, *int) {
+	robotsFetches := new(int)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		*robotsFetches++
+		if robots == "" {
+			w.WriteHeader(status)
+			return
+		}
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Write([]byte(robots))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/private/">p</a><a href="/public/">p</a>`))
+	})
+	// Normalize links to /private, which the mux redirects here.
+	mux.HandleFunc("/private/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("private"))
+	})
+	return httptest.NewServer(mux), robotsFetches
+}
+
+func TestRobotsFetcher(t *testing.T) {
+	tests := []struct {
+		name        string
+		robots      string
+		contentType string
+		status      int
+		closed      bool
+		fetched     []string
+		blocked     []string
+	}{
+		{
+			name:    "rules applied",
+			robots:  "User-agent: *\nDisallow: /private/\n",
+			fetched: []string{"/", "/public"},
+			blocked: []string{"/private"},
+		},
+		{
+			name:    "redirect target allowed",
+			robots:  "User-agent: *\nDisallow: /private/\nAllow: /private/$\n",
+			fetched: []string{"/", "/private", "/public"},
+		},
+		{
+			name:    "missing robots.txt allows all",
+			status:  http.StatusNotFound,
+			fetched: []string{"/", "/private", "/public"},
+		},
+		{
+			name:    "server error disallows all",
+			status:  http.StatusServiceUnavailable,
+			blocked: []string{"/"},
+		},
+		{
+			name:    "unreachable server disallows all",
+			closed:  true,
+			blocked: []string{"/"},
+		},
+		{
+			name:        "any content type",
+			robots:      "User-agent: *\nDisallow: /private\n",
+			contentType: "application/octet-stream",
+			fetched:     []string{"/", "/public"},
+			blocked:     []string{"/private"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, robotsFetches := robotsSite(tt.robots, tt.contentType, tt.status)
+			defer ts.Close()
+			if tt.closed {
+				ts.Close()
+			}
+			hf := &HTTPFetcher{}
+			f := &RobotsFetcher{Fetcher: WithContext(hf), UserAgent: "GopherBot"}
+			hf.Client = &http.Client{CheckRedirect: f.CheckRedirect}
+			c := NewCrawler(f)
+			c.Concurrency = 4
+
+			res, err := c.Run(context.Background(), ts.URL+"/", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fetched, blocked []string
+			for _, p := range res.Pages {
+				path := strings.TrimPrefix(p.URL, ts.URL)
+				switch {
+				case p.Err == nil:
+					fetched = append(fetched, path)
+				case errors.Is(p.Err, ErrDisallowed):
+					blocked = append(blocked, path)
+					if ratelimit.Unreachable(p.Err) {
+						t.Errorf("%s: %v reads as a transport failure", path, p.Err)
+					}
+				default:
+					t.Errorf("%s: unexpected error %v", path, p.Err)
+				}
+			}
+			sort.Strings(fetched)
+			sort.Strings(blocked)
+			if !reflect.DeepEqual(fetched, tt.fetched) {
+				t.Errorf("fetched %q, want %q", fetched, tt.fetched)
+			}
+			if !reflect.DeepEqual(blocked, tt.blocked) {
+				t.Errorf("blocked %q, want %q", blocked, tt.blocked)
+			}
+			want := 1
+			if tt.closed {
+				want = 0
+			}
+			if *robotsFetches != want {
+				t.Errorf("robots.txt fetched %d times, want %d", *robotsFetches, want)
+			}
+		})
+	}
+}
+
+// delayLimiter is a HostLimiter that robots.txt can slow down.
+type delayLimiter interface {
+	ratelimit.HostLimiter
+	DelaySetter
+}
+
+func TestRobotsCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter func(clock ratelimit.Clock) delayLimiter
+	}{
+		{"per host", func(clock ratelimit.Clock) delayLimiter { return ratelimit.NewPerHost(time.Second, 1, 0, clock) }},
+		{"adaptive", func(clock ratelimit.Clock) delayLimiter { return ratelimit.NewAdaptive(1, 10, clock) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, _ := robotsSite("User-agent: *\nCrawl-delay: 3\n", "", 0)
+			defer ts.Close()
+
+			clock := ratelimit.NewFakeClock(time.Unix(0, 0))
+			limiter := tt.limiter(clock)
+			c := NewCrawler(&RobotsFetcher{
+				Fetcher:   WithContext(&HTTPFetcher{}),
+				UserAgent: "GopherBot",
+				Limiter:   limiter,
+			})
+			c.Concurrency = 1
+			c.Limiter = limiter
+
+			var err error
+			clock.Drive(func() { _, err = c.Run(context.Background(), ts.URL+"/", 3) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Three pages: the first is free, robots.txt then spaces
+			// the other two 3s apart instead of 1s.
+			if got := clock.Now().Sub(time.Unix(0, 0)); got != 6*time.Second {
+				t.Errorf("crawl took %v on the fake clock, want 6s", got)
+			}
+		})
+	}
+}
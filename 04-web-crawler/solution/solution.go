@@ -10,29 +10,40 @@ type Fetcher interface {
 	Fetch(url string) (body string, urls []string, err error)
 }
 
-var (
-	m  = map[string]bool{}
-	mu sync.RWMutex
-)
+// Crawler remembers the URLs it has visited, so every Crawl call
+// gets its own visited set instead of sharing package-level state.
+type Crawler struct {
+	fetcher Fetcher
+	mu      sync.Mutex
+	visited map[string]bool
+}
+
+// claim marks url as visited and reports whether it was not visited
+// before. Checking and marking under one lock keeps two goroutines
+// from both fetching the same URL.
+func (c *Crawler) claim(url string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.visited[url] {
+		return false
+	}
+	c.visited[url] = true
+	return true
+}
 
 // Crawl uses fetcher to recursively crawl
 // pages starting with url, to a maximum of depth.
 func Crawl(url string, depth int, fetcher Fetcher) ([]string, error) {
-	if depth <= 0 {
-		return nil, nil
-	}
-	mu.RLock()
-	if m[url] {
-		mu.RUnlock()
+	c := &Crawler{fetcher: fetcher, visited: map[string]bool{}}
+	return c.Crawl(url, depth)
+}
+
+func (c *Crawler) Crawl(url string, depth int) ([]string, error) {
+	if depth <= 0 || !c.claim(url) {
 		return nil, nil
 	}
-	mu.RUnlock()
-
-	mu.Lock()
-	m[url] = true
-	mu.Unlock()
 
-	body, urls, err := fetcher.Fetch(url)
+	body, urls, err := c.fetcher.Fetch(url)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +55,7 @@ func Crawl(url string, depth int, fetcher Fetcher) ([]string, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if res, err := Crawl(u, depth-1, fetcher); err == nil {
+			if res, err := c.Crawl(u, depth-1); err == nil {
 				for _, b := range res {
 					ch <- b
 				}
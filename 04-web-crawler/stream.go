@@ -0,0 +1,45 @@
+package main
+
+import "context"
+
+// CrawlStream crawls like CrawlContext but sends every page on the
+// returned channel as soon as it is fetched, failed ones included.
+// The channel is unbuffered: fetching waits for the receiver, so a slow
+// receiver slows the crawl down. The channel is closed when the crawl
+// is over; to stop reading early, cancel ctx. Once it is closed, the
+// returned function gives the error the crawl stopped with, as Run
+// would return it; called earlier, it waits for the crawl to be over.
+func CrawlStream(ctx context.Context, url string, depth int, fetcher ContextFetcher) (<-chan Page, func() error) {
+	return NewCrawler(fetcher).Stream(ctx, url, depth)
+}
+
+// Stream is CrawlStream on c. If c has an OnPage callback it is
+// called before each page is sent, and its error stops the crawl.
+func (c *Crawler) Stream(ctx context.Context, url string, depth int) (<-chan Page, func() error) {
+	ch := make(chan Page)
+	done := make(chan struct{})
+	var err error
+	sc := *c
+	sc.OnPage = func(p Page) error {
+		if c.OnPage != nil {
+			if err := c.OnPage(p); err != nil {
+				return err
+			}
+		}
+		select {
+		case ch <- p:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	go func() {
+		defer close(ch)
+		defer close(done)
+		_, err = sc.Run(ctx, url, depth)
+	}()
+	return ch, func() error {
+		<-done
+		return err
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCrawlStream(t *testing.T) {
+	var urls []string
+	pages, wait := CrawlStream(context.Background(), "https://golang.org/", 4, WithContext(fetcher))
+	for p := range pages {
+		urls = append(urls, p.URL)
+	}
+	if err := wait(); err != nil {
+		t.Errorf("CrawlStream() error = %v", err)
+	}
+	sort.Strings(urls)
+	want := []string{
+		"https://golang.org/",
+		"https://golang.org/cmd/",
+		"https://golang.org/pkg/",
+		"https://golang.org/pkg/fmt/",
+		"https://golang.org/pkg/os/",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("CrawlStream() sent %q, want %q", urls, want)
+	}
+}
+
+// startFetcher counts the fetches started.
+type startFetcher struct {
+	Fetcher
+	mu      sync.Mutex
+	started int
+}
+
+func (f *startFetcher) Fetch(url string) (string, []string, error) {
+	f.mu.Lock()
+	f.started++
+	f.mu.Unlock()
+	return f.Fetcher.Fetch(url)
+}
+
+func (f *startFetcher) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.started
+}
+
+func TestCrawlStreamBackpressure(t *testing.T) {
+	f := &startFetcher{Fetcher: wideSite(50)}
+	c := NewCrawler(WithContext(f))
+	c.Concurrency = 4
+
+	received := 0
+	pages, _ := c.Stream(context.Background(), "root", 2)
+	for range pages {
+		received++
+		time.Sleep(time.Millisecond)
+		// Each worker may have fetched one page ahead,
+		// and the crawl loop may be holding one more.
+		if started := f.count(); started > received+c.Concurrency+1 {
+			t.Fatalf("%d fetches started with %d pages received", started, received)
+		}
+	}
+	if received != 51 {
+		t.Errorf("Stream() sent %d pages, want 51", received)
+	}
+}
+
+func TestCrawlStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, wait := CrawlStream(ctx, "root", 2, WithContext(wideSite(50)))
+	<-ch
+	cancel()
+
+	// The channel is closed even though nobody reads the pages.
+	timeout := time.After(time.Second)
+	for n := 0; ; n++ {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				if err := wait(); !errors.Is(err, context.Canceled) {
+					t.Errorf("CrawlStream() error = %v, want %v", err, context.Canceled)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("channel still open %d pages after cancel", n)
+		}
+	}
+}
+
+func TestCrawlStreamOnPageError(t *testing.T) {
+	errStop := errors.New("stop")
+	c := NewCrawler(WithContext(wideSite(50)))
+	c.Concurrency = 4
+	c.OnPage = func(p Page) error {
+		if p.URL == "page/2" {
+			return errStop
+		}
+		return nil
+	}
+
+	pages, wait := c.Stream(context.Background(), "root", 2)
+	for p := range pages {
+		if p.URL == "page/2" {
+			t.Errorf("Stream() sent %s, whose OnPage failed", p.URL)
+		}
+	}
+	if err := wait(); !errors.Is(err, errStop) {
+		t.Errorf("Stream() error = %v, want %v", err, errStop)
+	}
+}
+
+func TestOnPageError(t *testing.T) {
+	errStop := errors.New("stop")
+	f := &startFetcher{Fetcher: wideSite(50)}
+	c := NewCrawler(WithContext(f))
+	c.Concurrency = 4
+	calls := 0
+	c.OnPage = func(p Page) error {
+		if calls++; calls == 3 {
+			return errStop
+		}
+		return nil
+	}
+
+	res, err := c.Run(context.Background(), "root", 2)
+	if !errors.Is(err, errStop) {
+		t.Errorf("Run() error = %v, want %v", err, errStop)
+	}
+	if calls != 3 {
+		t.Errorf("OnPage called %d times, want 3", calls)
+	}
+	if started := f.count(); started > 3+c.Concurrency {
+		t.Errorf("%d fetches started, want at most %d", started, 3+c.Concurrency)
+	}
+	if len(res.Pages) != f.count() {
+		t.Errorf("Run() result has %d pages, %d were fetched", len(res.Pages), f.count())
+	}
+}
@@ -22,6 +22,40 @@ func (f fakeFetcher) Fetch(url string) (string, []string, error) {
 	return "", nil, fmt.Errorf("not found: %s", url)
 }
 
+// fetcher is a populated fakeFetcher.
+var fetcher = fakeFetcher{
+	"https://golang.org/": &fakeResult{
+		"The Go Programming Language",
+		[]string{
+			"https://golang.org/pkg/",
+			"https://golang.org/cmd/",
+		},
+	},
+	"https://golang.org/pkg/": &fakeResult{
+		"Packages",
+		[]string{
+			"https://golang.org/",
+			"https://golang.org/cmd/",
+			"https://golang.org/pkg/fmt/",
+			"https://golang.org/pkg/os/",
+		},
+	},
+	"https://golang.org/pkg/fmt/": &fakeResult{
+		"Package fmt",
+		[]string{
+			"https://golang.org/",
+			"https://golang.org/pkg/",
+		},
+	},
+	"https://golang.org/pkg/os/": &fakeResult{
+		"Package os",
+		[]string{
+			"https://golang.org/",
+			"https://golang.org/pkg/",
+		},
+	},
+}
+
 func TestCrawl(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -32,41 +66,10 @@ func TestCrawl(t *testing.T) {
 		err     error
 	}{
 		{
-			name:   "default",
-			url:    "https://golang.org/",
-			depths: 4,
-			fetcher: fakeFetcher{
-				"https://golang.org/": &fakeResult{
-					"The Go Programming Language",
-					[]string{
-						"https://golang.org/pkg/",
-						"https://golang.org/cmd/",
-					},
-				},
-				"https://golang.org/pkg/": &fakeResult{
-					"Packages",
-					[]string{
-						"https://golang.org/",
-						"https://golang.org/cmd/",
-						"https://golang.org/pkg/fmt/",
-						"https://golang.org/pkg/os/",
-					},
-				},
-				"https://golang.org/pkg/fmt/": &fakeResult{
-					"Package fmt",
-					[]string{
-						"https://golang.org/",
-						"https://golang.org/pkg/",
-					},
-				},
-				"https://golang.org/pkg/os/": &fakeResult{
-					"Package os",
-					[]string{
-						"https://golang.org/",
-						"https://golang.org/pkg/",
-					},
-				},
-			},
+			name:    "default",
+			url:     "https://golang.org/",
+			depths:  4,
+			fetcher: fetcher,
 			result: []string{
 				"The Go Programming Language",
 				"Packages",
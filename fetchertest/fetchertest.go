@@ -0,0 +1,163 @@
+// Package fetchertest records the fetches of a crawler and checks
+// them against the rules a crawler should keep: rate limits,
+// parallelism limits and fetching no URL twice.
+package fetchertest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+// Fetcher has the method set of the crawlers' Fetcher interfaces.
+type Fetcher interface {
+	Fetch(url string) (body string, urls []string, err error)
+}
+
+// Fetch is one recorded fetch.
+type Fetch struct {
+	URL        string
+	Start, End time.Time
+	// InFlight is the number of fetches running when this one
+	// started, itself included.
+	InFlight int
+	Err      error
+}
+
+// RecordingFetcher is a Fetcher that records every fetch it passes on
+// to Fetcher. It also has a FetchContext method, for crawlers that
+// can cancel fetches. It is safe for concurrent use.
+type RecordingFetcher struct {
+	Fetcher Fetcher
+	// Clock timestamps fetches. Nil means ratelimit.SystemClock.
+	Clock ratelimit.Clock
+	// Delay, if set, is slept on Clock before every fetch, so
+	// that fetches overlap.
+	Delay time.Duration
+
+	mu       sync.Mutex
+	fetches  []Fetch
+	inFlight int
+}
+
+func (f *RecordingFetcher) Fetch(url string) (string, []string, error) {
+	return f.FetchContext(context.Background(), url)
+}
+
+// FetchContext fetches url with Fetcher's FetchContext method if it
+// has one, and with Fetch otherwise.
+func (f *RecordingFetcher) FetchContext(ctx context.Context, url string) (string, []string, error) {
+	clock := f.clock()
+	f.mu.Lock()
+	f.inFlight++
+	i := len(f.fetches)
+	f.fetches = append(f.fetches, Fetch{URL: url, Start: clock.Now(), InFlight: f.inFlight})
+	f.mu.Unlock()
+
+	body, urls, err := f.fetch(ctx, url)
+
+	f.mu.Lock()
+	f.inFlight--
+	f.fetches[i].End = clock.Now()
+	f.fetches[i].Err = err
+	f.mu.Unlock()
+	return body, urls, err
+}
+
+func (f *RecordingFetcher) fetch(ctx context.Context, url string) (string, []string, error) {
+	if f.Delay > 0 {
+		if err := f.clock().Sleep(ctx, f.Delay); err != nil {
+			return "", nil, err
+		}
+	}
+	if cf, ok := f.Fetcher.(interface {
+		FetchContext(ctx context.Context, url string) (string, []string, error)
+	}); ok {
+		return cf.FetchContext(ctx, url)
+	}
+	return f.Fetcher.Fetch(url)
+}
+
+func (f *RecordingFetcher) clock() ratelimit.Clock {
+	if f.Clock == nil {
+		return ratelimit.SystemClock
+	}
+	return f.Clock
+}
+
+// Fetches returns the fetches so far, in the order they started.
+func (f *RecordingFetcher) Fetches() []Fetch {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.fetches)
+}
+
+// URLs returns the URLs fetched so far, in the order they started.
+func (f *RecordingFetcher) URLs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	urls := make([]string, len(f.fetches))
+	for i, fe := range f.fetches {
+		urls[i] = fe.URL
+	}
+	return urls
+}
+
+// Filter returns the fetches for which keep returns true.
+func Filter(fetches []Fetch, keep func(Fetch) bool) []Fetch {
+	var kept []Fetch
+	for _, fe := range fetches {
+		if keep(fe) {
+			kept = append(kept, fe)
+		}
+	}
+	return kept
+}
+
+// AssertMinSpacing fails the test unless fetches could have come
+// through a limiter allowing one fetch per interval with bursts of
+// burst, starting at start. Goroutines may read the clock late but
+// never early, so rather than compare neighbours it checks that the
+// k-th fetch to start (from 0) did so at least k-burst+1 intervals
+// after start.
+func AssertMinSpacing(t testing.TB, fetches []Fetch, start time.Time, interval time.Duration, burst int) {
+	t.Helper()
+	starts := make([]time.Time, len(fetches))
+	for i, fe := range fetches {
+		starts[i] = fe.Start
+	}
+	slices.SortFunc(starts, time.Time.Compare)
+	for k, ts := range starts {
+		if earliest := start.Add(time.Duration(k-burst+1) * interval); ts.Before(earliest) {
+			t.Errorf("fetch %d started at %v, want not before %v", k, ts.Sub(start), earliest.Sub(start))
+		}
+	}
+}
+
+// AssertMaxParallelism fails the test if more than n fetches
+// ever ran at once.
+func AssertMaxParallelism(t testing.TB, fetches []Fetch, n int) {
+	t.Helper()
+	most := 0
+	for _, fe := range fetches {
+		most = max(most, fe.InFlight)
+	}
+	if most > n {
+		t.Errorf("%d fetches ran at once, want at most %d", most, n)
+	}
+}
+
+// AssertFetchedOnce fails the test if a URL was fetched more than once.
+func AssertFetchedOnce(t testing.TB, fetches []Fetch) {
+	t.Helper()
+	seen := make(map[string]int)
+	for _, fe := range fetches {
+		if seen[fe.URL]++; seen[fe.URL] == 2 {
+			t.Errorf("%s fetched more than once", fe.URL)
+		}
+	}
+}
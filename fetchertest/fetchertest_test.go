@@ -0,0 +1,158 @@
+package fetchertest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+var epoch = time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+// site is a Fetcher that finds every URL, with no links.
+type site struct{}
+
+func (site) Fetch(url string) (string, []string, error) { return url, nil, nil }
+
+// gatedFetcher blocks every fetch until release is closed.
+type gatedFetcher struct {
+	release chan struct{}
+}
+
+func (f gatedFetcher) Fetch(url string) (string, []string, error) {
+	<-f.release
+	return url, nil, nil
+}
+
+func TestRecordingFetcher(t *testing.T) {
+	const n = 3
+	gate := gatedFetcher{make(chan struct{})}
+	f := &RecordingFetcher{Fetcher: gate}
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Fetch(fmt.Sprint(i))
+		}()
+	}
+	for len(f.Fetches()) < n {
+		time.Sleep(time.Millisecond)
+	}
+	close(gate.release)
+	wg.Wait()
+
+	fetches := f.Fetches()
+	most := 0
+	for _, fe := range fetches {
+		most = max(most, fe.InFlight)
+		if fe.End.Before(fe.Start) {
+			t.Errorf("%s ended at %v, before it started at %v", fe.URL, fe.End, fe.Start)
+		}
+	}
+	if most != n {
+		t.Errorf("Fetches() got most in flight = %d, want %d", most, n)
+	}
+	if got := len(f.URLs()); got != n {
+		t.Errorf("URLs() got %d URLs, want %d", got, n)
+	}
+}
+
+// recorder is a testing.TB that collects errors instead of failing.
+type recorder struct {
+	*testing.T
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	// fetches returns a fetch of each URL in urls, one every step
+	// from epoch, each with the given number in flight.
+	fetches := func(step time.Duration, inFlight int, urls ...string) []Fetch {
+		var fs []Fetch
+		for i, u := range urls {
+			at := epoch.Add(time.Duration(i) * step)
+			fs = append(fs, Fetch{URL: u, Start: at, End: at, InFlight: inFlight})
+		}
+		return fs
+	}
+	tests := []struct {
+		name   string
+		assert func(t testing.TB)
+		errors int
+	}{
+		{
+			name: "spaced",
+			assert: func(t testing.TB) {
+				AssertMinSpacing(t, fetches(time.Second, 1, "a", "b", "c"), epoch, time.Second, 1)
+			},
+		},
+		{
+			name: "too close",
+			assert: func(t testing.TB) {
+				AssertMinSpacing(t, fetches(time.Second/2, 1, "a", "b", "c"), epoch, time.Second, 1)
+			},
+			errors: 2,
+		},
+		{
+			name: "burst",
+			assert: func(t testing.TB) {
+				AssertMinSpacing(t, fetches(0, 1, "a", "b", "c"), epoch, time.Second, 3)
+			},
+		},
+		{
+			name: "parallel",
+			assert: func(t testing.TB) {
+				AssertMaxParallelism(t, fetches(0, 2, "a", "b"), 2)
+			},
+		},
+		{
+			name: "too parallel",
+			assert: func(t testing.TB) {
+				AssertMaxParallelism(t, fetches(0, 3, "a", "b", "c"), 2)
+			},
+			errors: 1,
+		},
+		{
+			name: "fetched once",
+			assert: func(t testing.TB) {
+				AssertFetchedOnce(t, fetches(0, 1, "a", "b", "c"))
+			},
+		},
+		{
+			name: "fetched thrice",
+			assert: func(t testing.TB) {
+				AssertFetchedOnce(t, fetches(0, 1, "a", "b", "a", "a"))
+			},
+			errors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{T: t}
+			tt.assert(r)
+			if len(r.errors) != tt.errors {
+				t.Errorf("got errors = %q, want %d", r.errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestRecordingFetcherDelay(t *testing.T) {
+	clock := ratelimit.NewFakeClock(epoch)
+	f := &RecordingFetcher{Fetcher: site{}, Clock: clock, Delay: time.Second}
+	clock.Drive(func() {
+		f.Fetch("a")
+		f.Fetch("b")
+	})
+	AssertMinSpacing(t, f.Fetches(), epoch, time.Second, 1)
+	if fe := f.Fetches()[0]; fe.End.Sub(fe.Start) != time.Second {
+		t.Errorf("fetch took %v, want %v", fe.End.Sub(fe.Start), time.Second)
+	}
+}
@@ -2,4 +2,6 @@ module concurrency
 
 go 1.24.5
 
+require golang.org/x/net v0.47.0
+
 require golang.org/x/tour v0.1.0 // indirect
@@ -0,0 +1,199 @@
+// Package pipeline runs values through concurrent stages connected by
+// channels: a Source emits them, each Stage transforms them on its own
+// pool of workers, and a Sink consumes them. It is the producer and
+// consumer pattern of the producerconsumer example with the plumbing
+// done once: starting goroutines, closing channels, fanning out and in,
+// and stopping everything on the first error.
+//
+//	p := pipeline.New(ctx)
+//	tweets := pipeline.From(p, source, 0)
+//	verdicts := pipeline.Then(p, tweets, pipeline.Stage[*Tweet, string]{Workers: 10, Func: classify})
+//	pipeline.To(p, verdicts, print)
+//	err := p.Wait()
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// Source emits the values that enter a pipeline, then returns nil.
+// emit fails once the pipeline is stopping; the Source should then
+// return emit's error.
+type Source[T any] func(ctx context.Context, emit func(T) error) error
+
+// Values returns a Source that emits vs.
+func Values[T any](vs ...T) Source[T] {
+	return func(ctx context.Context, emit func(T) error) error {
+		for _, v := range vs {
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Stage turns every value In into a value Out. Values are handed to
+// Workers goroutines, so with more than one worker they leave the
+// stage in the order they are done rather than the order they came.
+type Stage[In, Out any] struct {
+	Func func(ctx context.Context, v In) (Out, error)
+	// Workers is the number of values transformed at once.
+	// Less than 1 is 1.
+	Workers int
+	// Buffer is the number of values the stage's output channel
+	// holds before its workers block.
+	Buffer int
+}
+
+// Sink consumes the values that leave a pipeline.
+type Sink[T any] func(ctx context.Context, v T) error
+
+// Pipeline runs the goroutines of its sources, stages and sinks.
+// The first of them to fail stops the others. Every channel a
+// pipeline returns must be read, by a Stage, a Sink or the caller,
+// or Wait waits for ever.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+// New returns an empty Pipeline that stops when ctx is done.
+func New(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Wait waits for every goroutine of p to return and returns the first
+// error, or nil if the sources ran dry and every value went through.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+	return p.err
+}
+
+// run runs f in a goroutine of p. If f fails, p stops.
+func (p *Pipeline) run(f func() error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := f(); err != nil {
+			p.once.Do(func() {
+				p.err = err
+				p.cancel()
+			})
+		}
+	}()
+}
+
+// send sends v on ch unless p stops first.
+func send[T any](p *Pipeline, ch chan<- T, v T) error {
+	select {
+	case ch <- v:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// each calls f with every value received on in until in is closed or
+// p stops.
+func each[T any](p *Pipeline, in <-chan T, f func(T) error) error {
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return nil
+			}
+			if err := f(v); err != nil {
+				return err
+			}
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+}
+
+// From starts src in p and returns the channel it emits on,
+// which holds buffer values.
+func From[T any](p *Pipeline, src Source[T], buffer int) <-chan T {
+	out := make(chan T, buffer)
+	p.run(func() error {
+		defer close(out)
+		return src(p.ctx, func(v T) error { return send(p, out, v) })
+	})
+	return out
+}
+
+// Then starts s in p, reading from in, and returns
+// the channel its workers send on.
+func Then[In, Out any](p *Pipeline, in <-chan In, s Stage[In, Out]) <-chan Out {
+	out := make(chan Out, s.Buffer)
+	var workers sync.WaitGroup
+	for range max(s.Workers, 1) {
+		workers.Add(1)
+		p.run(func() error {
+			defer workers.Done()
+			return each(p, in, func(v In) error {
+				w, err := s.Func(p.ctx, v)
+				if err != nil {
+					return err
+				}
+				return send(p, out, w)
+			})
+		})
+	}
+	p.run(func() error {
+		workers.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// To starts sink in p, reading from in.
+func To[T any](p *Pipeline, in <-chan T, sink Sink[T]) {
+	p.run(func() error {
+		return each(p, in, func(v T) error { return sink(p.ctx, v) })
+	})
+}
+
+// Split fans out in to n channels, each holding buffer values. Every
+// value goes to one of them, whichever is ready first.
+func Split[T any](p *Pipeline, in <-chan T, n, buffer int) []<-chan T {
+	outs := make([]<-chan T, n)
+	for i := range outs {
+		out := make(chan T, buffer)
+		outs[i] = out
+		p.run(func() error {
+			defer close(out)
+			return each(p, in, func(v T) error { return send(p, out, v) })
+		})
+	}
+	return outs
+}
+
+// Merge fans ins in to one channel, holding buffer values,
+// which is closed once all of them are.
+func Merge[T any](p *Pipeline, buffer int, ins ...<-chan T) <-chan T {
+	out := make(chan T, buffer)
+	var senders sync.WaitGroup
+	for _, in := range ins {
+		senders.Add(1)
+		p.run(func() error {
+			defer senders.Done()
+			return each(p, in, func(v T) error { return send(p, out, v) })
+		})
+	}
+	p.run(func() error {
+		senders.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func square(ctx context.Context, n int) (int, error) { return n * n, nil }
+
+// collect returns a Sink appending to got, and got.
+func collect[T any]() (Sink[T], *[]T) {
+	var mu sync.Mutex
+	var got []T
+	return func(ctx context.Context, v T) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, v)
+		return nil
+	}, &got
+}
+
+func TestPipeline(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		buffer  int
+	}{
+		{name: "one worker", workers: 1},
+		{name: "no workers is one", workers: 0},
+		{name: "many workers", workers: 8},
+		{name: "buffered", workers: 3, buffer: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(context.Background())
+			sink, got := collect[int]()
+			ns := From(p, Values(1, 2, 3, 4, 5, 6), tt.buffer)
+			squares := Then(p, ns, Stage[int, int]{Func: square, Workers: tt.workers, Buffer: tt.buffer})
+			To(p, squares, sink)
+			if err := p.Wait(); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(*got)
+			if want := []int{1, 4, 9, 16, 25, 36}; !reflect.DeepEqual(*got, want) {
+				t.Errorf("Wait() got = %v, want %v", *got, want)
+			}
+		})
+	}
+}
+
+func TestPipelineWorkersRunAtOnce(t *testing.T) {
+	const workers = 4
+	// Every worker waits for all of them to be running: with
+	// fewer than workers at once the pipeline never finishes.
+	var running sync.WaitGroup
+	running.Add(workers)
+	p := New(context.Background())
+	ns := From(p, Values(1, 2, 3, 4), 0)
+	out := Then(p, ns, Stage[int, int]{Workers: workers, Func: func(ctx context.Context, n int) (int, error) {
+		running.Done()
+		running.Wait()
+		return n, nil
+	}})
+	sink, got := collect[int]()
+	To(p, out, sink)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*got) != workers {
+		t.Errorf("Wait() got %d values, want %d", len(*got), workers)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name   string
+		source Source[int]
+		stage  func(ctx context.Context, n int) (int, error)
+		sink   Sink[int]
+	}{
+		{
+			name: "source",
+			source: func(ctx context.Context, emit func(int) error) error {
+				emit(1)
+				return failed
+			},
+		},
+		{
+			name: "stage",
+			stage: func(ctx context.Context, n int) (int, error) {
+				if n == 3 {
+					return 0, failed
+				}
+				return n, nil
+			},
+		},
+		{
+			name: "sink",
+			sink: func(ctx context.Context, n int) error {
+				if n == 9 {
+					return failed
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The default source never runs dry,
+			// so only the error stops it.
+			source := func(ctx context.Context, emit func(int) error) error {
+				for n := 0; ; n++ {
+					if err := emit(n); err != nil {
+						return err
+					}
+				}
+			}
+			if tt.source != nil {
+				source = tt.source
+			}
+			stage := square
+			if tt.stage != nil {
+				stage = tt.stage
+			}
+			sink := Sink[int](func(ctx context.Context, n int) error { return nil })
+			if tt.sink != nil {
+				sink = tt.sink
+			}
+
+			p := New(context.Background())
+			out := Then(p, From(p, source, 0), Stage[int, int]{Func: stage, Workers: 3})
+			To(p, out, sink)
+			if err := p.Wait(); err != failed {
+				t.Errorf("Wait() got = %v, want %v", err, failed)
+			}
+		})
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+	forever := func(ctx context.Context, emit func(int) error) error {
+		for {
+			if err := emit(1); err != nil {
+				return err
+			}
+		}
+	}
+	out := Then(p, From(p, forever, 0), Stage[int, int]{Func: square, Workers: 2})
+	To(p, out, func(ctx context.Context, n int) error {
+		cancel()
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- p.Wait() }()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Wait() got = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after cancel")
+	}
+}
+
+func TestSplitMerge(t *testing.T) {
+	p := New(context.Background())
+	ns := From(p, Values(1, 2, 3, 4, 5, 6, 7, 8), 0)
+	parts := Split(p, ns, 3, 0)
+	squared := make([]<-chan int, len(parts))
+	for i, part := range parts {
+		squared[i] = Then(p, part, Stage[int, int]{Func: square})
+	}
+	sink, got := collect[int]()
+	To(p, Merge(p, 0, squared...), sink)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(*got)
+	if want := []int{1, 4, 9, 16, 25, 36, 49, 64}; !reflect.DeepEqual(*got, want) {
+		t.Errorf("Merge() got = %v, want %v", *got, want)
+	}
+}
@@ -9,50 +9,53 @@
 package main
 
 import (
+	"concurrency/pipeline"
 	. "concurrency/producerconsumer/utils"
+	"context"
 	"fmt"
-	"sync"
+	"log"
 	"time"
 )
 
-func producer(stream Stream, dataCh chan<- *Tweet, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for {
-		tweet, err := stream.Next()
-		if err == ErrEOF {
-			close(dataCh)
-			return
+// producer is a Source emitting the tweets of stream.
+func producer(stream Stream) pipeline.Source[*Tweet] {
+	return func(ctx context.Context, emit func(*Tweet) error) error {
+		for {
+			tweet, err := stream.Next()
+			if err == ErrEOF {
+				return nil
+			}
+			if err := emit(tweet); err != nil {
+				return err
+			}
 		}
-		dataCh <- tweet
 	}
 }
 
-func consumer(dataCh <-chan *Tweet, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for t := range dataCh {
-		if t.IsTalkingAboutGo() {
-			fmt.Println(t.Username, "\ttweets about golang")
-		} else {
-			fmt.Println(t.Username, "\tdoes not tweet about golang")
-		}
+// classify says whether t is about golang.
+func classify(ctx context.Context, t *Tweet) (string, error) {
+	if t.IsTalkingAboutGo() {
+		return t.Username + "\ttweets about golang", nil
 	}
+	return t.Username + "\tdoes not tweet about golang", nil
+}
+
+// consumer prints the verdicts.
+func consumer(ctx context.Context, verdict string) error {
+	fmt.Println(verdict)
+	return nil
 }
 
 func main() {
 	start := time.Now()
-	stream := GetMockStream()
-	dataCh := make(chan *Tweet)
-	var wg sync.WaitGroup
-
-	// Producer
-	wg.Add(1)
-	go producer(stream, dataCh, &wg)
 
-	// Consumer
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
-		go consumer(dataCh, &wg)
+	// Stream -> classify on 10 workers -> print
+	p := pipeline.New(context.Background())
+	tweets := pipeline.From(p, producer(GetMockStream()), 0)
+	verdicts := pipeline.Then(p, tweets, pipeline.Stage[*Tweet, string]{Func: classify, Workers: 10})
+	pipeline.To(p, verdicts, consumer)
+	if err := p.Wait(); err != nil {
+		log.Fatal(err)
 	}
-	wg.Wait()
 	fmt.Printf("Process took %s\n", time.Since(start))
 }
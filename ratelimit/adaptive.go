@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Observer is a HostLimiter that learns from the responses to the
+// requests it let through. Crawlers call Observe after every fetch.
+type Observer interface {
+	HostLimiter
+	Observe(host string, r Response)
+}
+
+// Response is how a request went, as far as an Observer cares.
+type Response struct {
+	Latency time.Duration
+	// Err is the request's error, nil on success.
+	Err error
+	// StatusCode is the HTTP status, or 0 if there was none.
+	StatusCode int
+	// RetryAfter is the delay the server asked for, or 0.
+	RetryAfter time.Duration
+}
+
+// RateChange is a point in the rate history of a host.
+type RateChange struct {
+	Time time.Time `json:"time"`
+	// Rate is the new rate in requests per second.
+	Rate float64 `json:"rate"`
+	// Reason is what caused the change: "start" for the first
+	// entry, "ok" for a good response, "slow", "error" or the status
+	// code for a bad one, "retry-after" when the server asked
+	// to wait and "crawl-delay" when robots.txt capped the rate.
+	Reason string `json:"reason"`
+}
+
+// Adaptive is an Observer that sets each host's rate by additive
+// increase, multiplicative decrease: every good response adds Increase
+// requests per second, up to the ceiling, and every bad one multiplies
+// the rate by Decrease, down to the floor. A response is bad if it is
+// 429 Too Many Requests, a 5xx, an error for which Unreachable is
+// true, or slower than SlowLatency. Other errors, such as a 404 or a
+// page of the wrong type, say nothing about load and leave the rate
+// as it is.
+// A Retry-After holds back every request to the host until it is over.
+//
+// New hosts start at the floor. Set the exported fields before use.
+type Adaptive struct {
+	// Increase is added to the rate per good response,
+	// in requests per second.
+	Increase float64
+	// Decrease multiplies the rate per bad response.
+	Decrease float64
+	// SlowLatency, if not zero, makes slower responses bad.
+	SlowLatency time.Duration
+
+	floor, ceiling float64
+	clock          Clock
+
+	mu    sync.Mutex
+	hosts map[string]*adaptiveHost
+}
+
+type adaptiveHost struct {
+	bucket  *TokenBucket
+	rate    float64
+	ceiling float64   // the Adaptive's, or lower after SetDelay
+	paused  time.Time // no requests before then
+	history []RateChange
+}
+
+// NewAdaptive returns an Adaptive keeping every host between floor
+// and ceiling requests per second; floor must be above zero. Increase
+// starts at a tenth of the ceiling and Decrease at one half.
+func NewAdaptive(floor, ceiling float64, clock Clock) *Adaptive {
+	return &Adaptive{
+		Increase: ceiling / 10,
+		Decrease: 0.5,
+		floor:    floor,
+		ceiling:  ceiling,
+		clock:    clock,
+		hosts:    make(map[string]*adaptiveHost),
+	}
+}
+
+func (a *Adaptive) Acquire(ctx context.Context, host string) (func(), error) {
+	a.mu.Lock()
+	h := a.host(host)
+	wait := h.paused.Sub(a.clock.Now())
+	a.mu.Unlock()
+	if err := a.clock.Sleep(ctx, wait); err != nil {
+		return nil, err
+	}
+	if err := h.bucket.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+func (a *Adaptive) Observe(host string, r Response) {
+	if errors.Is(r.Err, context.Canceled) || errors.Is(r.Err, context.DeadlineExceeded) {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h := a.host(host)
+	now := a.clock.Now()
+
+	if r.RetryAfter > 0 {
+		h.paused = now.Add(r.RetryAfter)
+		h.history = append(h.history, RateChange{now, h.rate, "retry-after"})
+	}
+	var reason string
+	switch {
+	case r.StatusCode == http.StatusTooManyRequests || r.StatusCode >= 500:
+		reason = strconv.Itoa(r.StatusCode)
+	case r.Err != nil && r.StatusCode == 0 && Unreachable(r.Err):
+		reason = "error"
+	case r.Err != nil:
+		return
+	case a.SlowLatency > 0 && r.Latency > a.SlowLatency:
+		reason = "slow"
+	}
+	rate := min(h.rate+a.Increase, h.ceiling)
+	if reason != "" {
+		rate = max(h.rate*a.Decrease, min(a.floor, h.ceiling))
+	} else {
+		reason = "ok"
+	}
+	h.set(now, rate, reason)
+}
+
+// SetDelay caps host's rate at one request per d, as asked for by a
+// robots.txt Crawl-delay. The cap wins over the floor.
+func (a *Adaptive) SetDelay(host string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h := a.host(host)
+	h.ceiling = min(a.ceiling, float64(time.Second)/float64(d))
+	if h.rate > h.ceiling {
+		h.set(a.clock.Now(), h.ceiling, "crawl-delay")
+	}
+}
+
+// set changes the rate to rate, logging the change.
+func (h *adaptiveHost) set(now time.Time, rate float64, reason string) {
+	if rate == h.rate {
+		return
+	}
+	h.rate = rate
+	h.bucket.SetInterval(time.Duration(float64(time.Second) / rate))
+	h.history = append(h.history, RateChange{now, rate, reason})
+}
+
+// Unreachable reports whether err is a transport failure: the server
+// could not be reached or did not answer in time, as with a refused
+// connection, a failed DNS lookup or a timeout. Only those errors tell
+// anything about a server's load.
+func Unreachable(err error) bool {
+	var ne net.Error
+	var ue *url.Error
+	return errors.As(err, &ne) || errors.As(err, &ue) || errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// Rate returns host's current rate in requests per second.
+func (a *Adaptive) Rate(host string) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.host(host).rate
+}
+
+// History returns the rate history of every host seen so far,
+// starting with the floor it began at.
+func (a *Adaptive) History() map[string][]RateChange {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	hist := make(map[string][]RateChange, len(a.hosts))
+	for name, h := range a.hosts {
+		hist[name] = slices.Clone(h.history)
+	}
+	return hist
+}
+
+// host returns the state of host, creating it at the floor rate.
+// a.mu must be held.
+func (a *Adaptive) host(name string) *adaptiveHost {
+	h := a.hosts[name]
+	if h == nil {
+		h = &adaptiveHost{
+			bucket:  NewTokenBucket(time.Duration(float64(time.Second)/a.floor), 1, a.clock),
+			rate:    a.floor,
+			ceiling: a.ceiling,
+		}
+		h.history = []RateChange{{a.clock.Now(), a.floor, "start"}}
+		a.hosts[name] = h
+	}
+	return h
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveObserve(t *testing.T) {
+	failed := errors.New("failed")
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name      string
+		responses []Response
+		rate      float64
+		reasons   []string
+	}{
+		{
+			name:      "good responses add up to the ceiling",
+			responses: []Response{{}, {}, {}, {}, {}},
+			rate:      4,
+			reasons:   []string{"start", "ok", "ok", "ok"},
+		},
+		{
+			name:      "429 halves",
+			responses: []Response{{}, {}, {StatusCode: http.StatusTooManyRequests, Err: failed}},
+			rate:      1.5,
+			reasons:   []string{"start", "ok", "ok", "429"},
+		},
+		{
+			name:      "never below the floor",
+			responses: []Response{{StatusCode: 503, Err: failed}, {Err: refused}},
+			rate:      1,
+			reasons:   []string{"start"},
+		},
+		{
+			name:      "unreachable server and slow responses",
+			responses: []Response{{}, {}, {}, {Err: refused}, {Latency: time.Second}},
+			rate:      1,
+			reasons:   []string{"start", "ok", "ok", "ok", "error", "slow"},
+		},
+		{
+			name:      "not found says nothing",
+			responses: []Response{{}, {StatusCode: 404, Err: failed}},
+			rate:      2,
+			reasons:   []string{"start", "ok"},
+		},
+		{
+			name:      "other errors say nothing",
+			responses: []Response{{}, {Err: failed}, {Err: errors.New("unsupported content type")}},
+			rate:      2,
+			reasons:   []string{"start", "ok"},
+		},
+		{
+			name:      "cancelled says nothing",
+			responses: []Response{{Err: context.Canceled}},
+			rate:      1,
+			reasons:   []string{"start"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAdaptive(1, 4, NewFakeClock(epoch))
+			a.Increase = 1
+			a.SlowLatency = 500 * time.Millisecond
+			for _, r := range tt.responses {
+				a.Observe("example.com", r)
+			}
+			if got := a.Rate("example.com"); got != tt.rate {
+				t.Errorf("Rate() got = %v, want %v", got, tt.rate)
+			}
+			var reasons []string
+			for _, rc := range a.History()["example.com"] {
+				reasons = append(reasons, rc.Reason)
+			}
+			if len(reasons) != len(tt.reasons) {
+				t.Fatalf("History() reasons = %v, want %v", reasons, tt.reasons)
+			}
+			for i := range reasons {
+				if reasons[i] != tt.reasons[i] {
+					t.Errorf("History() reasons = %v, want %v", reasons, tt.reasons)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestAdaptiveAcquire(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	a := NewAdaptive(1, 10, clock)
+	a.Increase = 9
+	ctx := context.Background()
+
+	clock.Drive(func() {
+		// At the floor, one request per second.
+		a.Acquire(ctx, "example.com")
+		a.Acquire(ctx, "example.com")
+		if got := clock.Now().Sub(epoch); got != time.Second {
+			t.Errorf("two Acquires at the floor took %v, want %v", got, time.Second)
+		}
+
+		// At the ceiling, ten.
+		a.Observe("example.com", Response{})
+		start := clock.Now()
+		for range 10 {
+			a.Acquire(ctx, "example.com")
+		}
+		if got := clock.Now().Sub(start); got > time.Second+time.Millisecond {
+			t.Errorf("ten Acquires at the ceiling took %v, want about %v", got, time.Second)
+		}
+
+		// Retry-After holds the host back, but not others.
+		a.Observe("example.com", Response{StatusCode: 503, RetryAfter: 30 * time.Second, Err: errors.New("busy")})
+		start = clock.Now()
+		a.Acquire(ctx, "other.example")
+		if got := clock.Now().Sub(start); got != 0 {
+			t.Errorf("Acquire() of another host waited %v", got)
+		}
+		a.Acquire(ctx, "example.com")
+		if got := clock.Now().Sub(start); got < 30*time.Second {
+			t.Errorf("Acquire() after Retry-After waited %v, want at least %v", got, 30*time.Second)
+		}
+	})
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"url error", &url.Error{Op: "Get", URL: "http://example.com/", Err: io.EOF}, true},
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.invalid"}, true},
+		{"timeout", fmt.Errorf("read: %w", os.ErrDeadlineExceeded), true},
+		{"other", errors.New("not found: http://example.com/"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unreachable(tt.err); got != tt.want {
+				t.Errorf("Unreachable() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveSetDelay(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	a := NewAdaptive(1, 10, clock)
+	a.Increase = 9
+	a.Observe("example.com", Response{})
+
+	// A Crawl-delay of 2s caps the rate at half a request per
+	// second, below the floor, whatever the responses say.
+	a.SetDelay("example.com", 2*time.Second)
+	a.Observe("example.com", Response{})
+	a.Observe("example.com", Response{StatusCode: 503, Err: errors.New("busy")})
+	if got := a.Rate("example.com"); got != 0.5 {
+		t.Errorf("Rate() got = %v, want %v", got, 0.5)
+	}
+	hist := a.History()["example.com"]
+	if got := hist[len(hist)-1].Reason; got != "crawl-delay" {
+		t.Errorf("History() ends with %q, want %q", got, "crawl-delay")
+	}
+
+	clock.Drive(func() {
+		ctx := context.Background()
+		a.Acquire(ctx, "example.com")
+		start := clock.Now()
+		a.Acquire(ctx, "example.com")
+		if got := clock.Now().Sub(start); got != 2*time.Second {
+			t.Errorf("Acquire() after SetDelay waited %v, want %v", got, 2*time.Second)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket allows one event per interval on average, with bursts
+// of up to burst events. The bucket starts full.
+type TokenBucket struct {
+	interval time.Duration
+	burst    int
+	clock    Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a TokenBucket that refills one token every
+// interval and holds at most burst tokens. A burst below 1 is 1.
+func NewTokenBucket(interval time.Duration, burst int, clock Clock) *TokenBucket {
+	burst = max(burst, 1)
+	return &TokenBucket{
+		interval: interval,
+		burst:    burst,
+		clock:    clock,
+		tokens:   float64(burst),
+		last:     clock.Now(),
+	}
+}
+
+// Wait blocks until a token is available and takes it.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		d := b.take()
+		if d == 0 {
+			return nil
+		}
+		if err := b.clock.Sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// SetInterval changes how often the bucket refills. Tokens
+// earned under the old interval are kept, except that a bucket with
+// no interval, which is always full, starts again empty.
+func (b *TokenBucket) SetInterval(interval time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.interval == 0 && interval > 0 {
+		b.tokens = 0
+	}
+	b.interval = interval
+}
+
+// take takes a token if there is one and returns 0,
+// otherwise it returns how long until the next token.
+func (b *TokenBucket) take() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(b.interval))
+}
+
+func (b *TokenBucket) refill() {
+	now := b.clock.Now()
+	if b.interval > 0 {
+		b.tokens += float64(now.Sub(b.last)) / float64(b.interval)
+	} else {
+		b.tokens = float64(b.burst)
+	}
+	b.tokens = min(b.tokens, float64(b.burst))
+	b.last = now
+}
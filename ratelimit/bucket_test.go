@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var epoch = time.Unix(0, 0)
+
+// checkSpacing fails the test if times could not have come from a
+// limiter allowing one event per interval with bursts of burst: the
+// k-th event (from 0) must not happen before (k-burst+1) intervals.
+func checkSpacing(t *testing.T, name string, times []time.Time, start time.Time, interval time.Duration, burst int) {
+	t.Helper()
+	times = slices.Clone(times)
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	for k, ts := range times {
+		if earliest := start.Add(time.Duration(k-burst+1) * interval); ts.Before(earliest) {
+			t.Errorf("%s: event %d at %v, want not before %v", name, k, ts.Sub(start), earliest.Sub(start))
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		burst    int
+		events   int
+		want     time.Duration // time on the clock after the last event
+	}{
+		{"one per second", time.Second, 1, 5, 4 * time.Second},
+		{"burst of three", time.Second, 3, 5, 2 * time.Second},
+		{"zero burst is one", time.Second, 0, 3, 2 * time.Second},
+		{"no interval", 0, 1, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(epoch)
+			b := NewTokenBucket(tt.interval, tt.burst, clock)
+			var times []time.Time
+			clock.Drive(func() {
+				for range tt.events {
+					if err := b.Wait(context.Background()); err != nil {
+						t.Fatal(err)
+					}
+					times = append(times, clock.Now())
+				}
+			})
+			checkSpacing(t, "Wait()", times, epoch, tt.interval, max(tt.burst, 1))
+			if got := clock.Now().Sub(epoch); got != tt.want {
+				t.Errorf("Wait() took %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	b := NewTokenBucket(time.Second, 2, clock)
+	clock.Drive(func() {
+		for range 2 {
+			b.Wait(context.Background())
+		}
+		// An idle bucket refills, but never beyond burst.
+		clock.Advance(10 * time.Second)
+		start := clock.Now()
+		for range 3 {
+			b.Wait(context.Background())
+		}
+		if got := clock.Now().Sub(start); got != time.Second {
+			t.Errorf("Wait() after idle took %v, want %v", got, time.Second)
+		}
+	})
+}
+
+func TestTokenBucketSetInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"from one second", time.Second},
+		{"from no interval", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(epoch)
+			b := NewTokenBucket(tt.interval, 1, clock)
+			var got []time.Duration
+			clock.Drive(func() {
+				b.Wait(context.Background())
+				b.SetInterval(2 * time.Second)
+				// The token taken before the change stays taken.
+				for range 2 {
+					b.Wait(context.Background())
+					got = append(got, clock.Now().Sub(epoch))
+				}
+			})
+			if want := []time.Duration{2 * time.Second, 4 * time.Second}; !slices.Equal(got, want) {
+				t.Errorf("Wait() returned at %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketContext(t *testing.T) {
+	b := NewTokenBucket(time.Hour, 1, SystemClock)
+	b.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
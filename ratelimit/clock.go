@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits. Limiters take one so tests
+// can swap the system clock for a FakeClock.
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for d, or returns ctx.Err() if ctx is done first.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock is the Clock backed by package time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FakeClock is a Clock for tests. Its time only moves when Advance
+// is called: Sleep blocks until another goroutine advances the clock
+// to the end of the sleep. Drive does so for the code under test, as
+// fast as that code lets it, so that code runs as it would in real
+// time but without waiting.
+type FakeClock struct {
+	mu       sync.Mutex
+	now      time.Time
+	sleepers []*fakeSleeper
+	moves    int // sleeps begun or ended, to tell when goroutines settle
+}
+
+type fakeSleeper struct {
+	until time.Time
+	wake  chan struct{}
+}
+
+// settle is how long Drive waits without a sleep beginning or ending
+// before it takes the goroutines it drives to be blocked.
+const settle = time.Millisecond
+
+// NewFakeClock returns a FakeClock set to start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	s := &fakeSleeper{until: c.now.Add(d), wake: make(chan struct{})}
+	c.sleepers = append(c.sleepers, s)
+	c.moves++
+	c.mu.Unlock()
+
+	select {
+	case <-s.wake:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		c.sleepers = slices.DeleteFunc(c.sleepers, func(o *fakeSleeper) bool { return o == s })
+		c.moves++
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Advance moves the clock forward by d, waking the sleeps
+// that end by then.
+func (c *FakeClock) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Drive calls f and, until it returns, moves the clock on to the
+// first end of a sleep whenever the goroutines sleeping have settled,
+// that is when no sleep has begun or ended for a while. Sleeps ending
+// at the same time end together, as they would in real time, so
+// goroutines that each wait on their own rather than take turns are
+// seen fetching together.
+func (c *FakeClock) Drive(f func()) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		tick := time.NewTicker(settle)
+		defer tick.Stop()
+		last := -1
+		for {
+			select {
+			case <-stop:
+				return
+			case <-tick.C:
+			}
+			c.mu.Lock()
+			if c.moves == last && len(c.sleepers) > 0 {
+				first := c.sleepers[0].until
+				for _, s := range c.sleepers[1:] {
+					if s.until.Before(first) {
+						first = s.until
+					}
+				}
+				c.set(first)
+			}
+			last = c.moves
+			c.mu.Unlock()
+		}
+	}()
+	f()
+}
+
+// set moves the clock to t and wakes the sleeps that end by then.
+// c.mu must be held.
+func (c *FakeClock) set(t time.Time) {
+	c.now = t
+	c.sleepers = slices.DeleteFunc(c.sleepers, func(s *fakeSleeper) bool {
+		if s.until.After(t) {
+			return false
+		}
+		close(s.wake)
+		c.moves++
+		return true
+	})
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFakeClockSleep(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	done := make(chan error)
+	go func() { done <- clock.Sleep(context.Background(), time.Second) }()
+
+	// Sleep waits for the clock to move, however long that takes.
+	select {
+	case err := <-done:
+		t.Fatalf("Sleep() returned %v before the clock moved", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Sleep() returned %v a millisecond early", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Errorf("Sleep() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- clock.Sleep(ctx, time.Second) }()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFakeClockDrive(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	var (
+		mu    sync.Mutex
+		woken []time.Duration
+	)
+	clock.Drive(func() {
+		var wg sync.WaitGroup
+		for _, d := range []time.Duration{time.Second, time.Second, 3 * time.Second} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				clock.Sleep(context.Background(), d)
+				mu.Lock()
+				woken = append(woken, clock.Now().Sub(epoch))
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+	})
+	// Sleepers that wait on their own wake together, as they
+	// would in real time, rather than one after the other.
+	if got := clock.Now().Sub(epoch); got != 3*time.Second {
+		t.Errorf("Drive() took the clock to %v, want %v", got, 3*time.Second)
+	}
+	if woken[0] != time.Second || woken[1] != time.Second {
+		t.Errorf("Sleep() woke at %v, want the first two at 1s", woken)
+	}
+}
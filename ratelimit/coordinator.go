@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server hands out the events of one Limiter to clients on other
+// connections, so that crawler processes sharing a Server share its
+// rate instead of adding up theirs. The protocol is one line per
+// request, "wait", answered once the Limiter allows it by "ok", or by
+// "err" and a message if its Wait failed.
+type Server struct {
+	Limiter Limiter
+}
+
+// Serve accepts connections on ln until ln is closed.
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	// Reading runs apart from waiting, so a client that hangs up
+	// while it waits cancels its Wait and frees its place.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reqs := make(chan string)
+	go func() {
+		defer cancel()
+		sc := bufio.NewScanner(conn)
+		for sc.Scan() {
+			select {
+			case reqs <- sc.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var req string
+		select {
+		case req = <-reqs:
+		case <-ctx.Done():
+			return
+		}
+		reply := "ok"
+		if req != "wait" {
+			reply = fmt.Sprintf("err unknown request %q", req)
+		} else if err := s.Limiter.Wait(ctx); ctx.Err() != nil {
+			return
+		} else if err != nil {
+			reply = "err " + err.Error()
+		}
+		if _, err := fmt.Fprintln(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// Client is a Limiter that leases its events from a Server.
+// It is safe for concurrent use; its Waits are sent one at a time.
+type Client struct {
+	network, addr string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Dial connects to the Server at addr on network, "tcp" or "unix".
+func Dial(network, addr string) (*Client, error) {
+	c := &Client{network: network, addr: addr}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) connect() error {
+	conn, err := net.Dial(c.network, c.addr)
+	if err != nil {
+		return err
+	}
+	c.conn, c.r = conn, bufio.NewReader(conn)
+	return nil
+}
+
+// Wait asks the server for an event and waits for it. If ctx is done
+// first the connection is dropped, which cancels the server's Wait,
+// and the next Wait reconnects.
+func (c *Client) Wait(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	_, err := fmt.Fprintln(c.conn, "wait")
+	var reply string
+	if err == nil {
+		reply, err = c.r.ReadString('\n')
+	}
+	if !stop() || err != nil {
+		// The deadline may be set: the connection is spent.
+		c.conn.Close()
+		c.conn = nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	reply = strings.TrimSuffix(reply, "\n")
+	if reply == "ok" {
+		return nil
+	}
+	msg, _ := strings.CutPrefix(reply, "err ")
+	if msg == ErrBucketFull.Error() {
+		return ErrBucketFull
+	}
+	return fmt.Errorf("ratelimit: server: %s", msg)
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serve starts a Server for l on network and returns its address.
+func serve(t *testing.T, network, addr string, l Limiter) string {
+	t.Helper()
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go (&Server{Limiter: l}).Serve(ln)
+	return ln.Addr().String()
+}
+
+// waitAll runs waits Waits on each of clients at once and
+// returns the clock time after each.
+func waitAll(t *testing.T, clients []*Client, waits int, clock Clock) []time.Time {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		times []time.Time
+		wg    sync.WaitGroup
+	)
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range waits {
+				if err := c.Wait(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				times = append(times, clock.Now())
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return times
+}
+
+func dialAll(t *testing.T, network, addr string, n int) []*Client {
+	t.Helper()
+	var clients []*Client
+	for range n {
+		c, err := Dial(network, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+func TestServerSharedRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter func(clock Clock) Limiter
+		burst   int
+	}{
+		{"interval", func(clock Clock) Limiter { return NewInterval(time.Second, clock) }, 1},
+		{"token bucket", func(clock Clock) Limiter { return NewTokenBucket(time.Second, 3, clock) }, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(epoch)
+			addr := serve(t, "tcp", "127.0.0.1:0", tt.limiter(clock))
+			var times []time.Time
+			clock.Drive(func() {
+				times = waitAll(t, dialAll(t, "tcp", addr, 4), 5, clock)
+			})
+			if len(times) != 20 {
+				t.Fatalf("got %d events, want 20", len(times))
+			}
+			checkSpacing(t, "Wait()", times, epoch, time.Second, tt.burst)
+		})
+	}
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	sock := filepath.Join(t.TempDir(), "limiter.sock")
+	serve(t, "unix", sock, NewInterval(interval, SystemClock))
+
+	start := time.Now()
+	times := waitAll(t, dialAll(t, "unix", sock, 3), 4, SystemClock)
+	checkSpacing(t, "Wait()", times, start, interval, 1)
+}
+
+func TestClientCancel(t *testing.T) {
+	addr := serve(t, "tcp", "127.0.0.1:0", NewTokenBucket(time.Hour, 1, SystemClock))
+	c := dialAll(t, "tcp", addr, 1)[0]
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err := c.Wait(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	}
+}
+
+func TestClientServerError(t *testing.T) {
+	addr := serve(t, "tcp", "127.0.0.1:0", NewLeakyBucket(time.Hour, 1, SystemClock))
+	c := dialAll(t, "tcp", addr, 1)[0]
+	c.Wait(context.Background())
+	if err := c.Wait(context.Background()); !errors.Is(err, ErrBucketFull) {
+		t.Errorf("Wait() error = %v, want %v", err, ErrBucketFull)
+	}
+}
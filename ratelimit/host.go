@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HostLimiter limits requests per host. Acquire blocks until a
+// request to host may start; the caller must call release once the
+// request is done.
+type HostLimiter interface {
+	Acquire(ctx context.Context, host string) (release func(), err error)
+}
+
+// PerHost is a HostLimiter that gives every host its own TokenBucket
+// and caps how many requests to one host run at the same time.
+type PerHost struct {
+	interval      time.Duration
+	burst         int
+	maxConcurrent int
+	clock         Clock
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	bucket *TokenBucket
+	slots  chan struct{} // nil when concurrency is not capped
+}
+
+// NewPerHost returns a PerHost allowing each host one request per
+// interval with bursts of burst requests, and at most maxConcurrent
+// requests in flight per host. maxConcurrent <= 0 means no cap.
+func NewPerHost(interval time.Duration, burst, maxConcurrent int, clock Clock) *PerHost {
+	return &PerHost{
+		interval:      interval,
+		burst:         burst,
+		maxConcurrent: maxConcurrent,
+		clock:         clock,
+		hosts:         make(map[string]*hostState),
+	}
+}
+
+func (p *PerHost) Acquire(ctx context.Context, host string) (func(), error) {
+	h := p.host(host)
+	if h.slots == nil {
+		if err := h.bucket.Wait(ctx); err != nil {
+			return nil, err
+		}
+		return func() {}, nil
+	}
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := h.bucket.Wait(ctx); err != nil {
+		<-h.slots
+		return nil, err
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-h.slots }) }, nil
+}
+
+// SetDelay makes host's bucket refill once every d, as asked for by a
+// robots.txt Crawl-delay. Requests still come in bursts of up to the
+// burst given to NewPerHost, then d apart; without an interval given
+// to NewPerHost, the next request waits d. It never makes a host
+// faster than the interval given to NewPerHost.
+func (p *PerHost) SetDelay(host string, d time.Duration) {
+	p.host(host).bucket.SetInterval(max(d, p.interval))
+}
+
+func (p *PerHost) host(name string) *hostState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.hosts[name]
+	if h == nil {
+		h = &hostState{bucket: NewTokenBucket(p.interval, p.burst, p.clock)}
+		if p.maxConcurrent > 0 {
+			h.slots = make(chan struct{}, p.maxConcurrent)
+		}
+		p.hosts[name] = h
+	}
+	return h
+}
+
+// Host returns the host part of rawURL, or rawURL itself
+// if it has none.
+func Host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPerHostSpacing(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	p := NewPerHost(time.Second, 2, 0, clock)
+
+	var (
+		mu    sync.Mutex
+		times = map[string][]time.Time{}
+		wg    sync.WaitGroup
+	)
+	clock.Drive(func() {
+		for _, host := range []string{"a.example", "b.example", "c.example"} {
+			for range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					release, err := p.Acquire(context.Background(), host)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					defer release()
+					mu.Lock()
+					times[host] = append(times[host], clock.Now())
+					mu.Unlock()
+				}()
+			}
+		}
+		wg.Wait()
+	})
+
+	for host, ts := range times {
+		if len(ts) != 10 {
+			t.Errorf("%s: got %d requests, want 10", host, len(ts))
+		}
+		checkSpacing(t, host, ts, epoch, time.Second, 2)
+	}
+}
+
+func TestPerHostIndependent(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	p := NewPerHost(time.Second, 1, 0, clock)
+	for _, host := range []string{"a.example", "b.example", "c.example"} {
+		release, err := p.Acquire(context.Background(), host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if got := clock.Now().Sub(epoch); got != 0 {
+		t.Errorf("first request to each host waited %v, want 0", got)
+	}
+}
+
+func TestPerHostConcurrency(t *testing.T) {
+	p := NewPerHost(0, 1, 2, SystemClock)
+	ctx := context.Background()
+
+	r1, _ := p.Acquire(ctx, "a.example")
+	r2, _ := p.Acquire(ctx, "a.example")
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(short, "a.example"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("third Acquire() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if r, err := p.Acquire(ctx, "b.example"); err != nil {
+		t.Errorf("Acquire() other host error = %v", err)
+	} else {
+		r()
+	}
+
+	r1()
+	r1() // release is idempotent
+	r3, err := p.Acquire(ctx, "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2()
+	r3()
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://golang.org/pkg/", "golang.org"},
+		{"http://example.com:8080/a?b", "example.com:8080"},
+		{"page/1", "page/1"},
+	}
+	for _, tt := range tests {
+		if got := Host(tt.url); got != tt.want {
+			t.Errorf("Host(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestPerHostSetDelay(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	p := NewPerHost(time.Second, 1, 0, clock)
+	p.SetDelay("slow.example", 5*time.Second)
+	p.SetDelay("fast.example", time.Millisecond)
+
+	tests := []struct {
+		host string
+		want time.Duration
+	}{
+		{"slow.example", 5 * time.Second},
+		{"fast.example", time.Second},
+		{"other.example", time.Second},
+	}
+	clock.Drive(func() {
+		for _, tt := range tests {
+			start := clock.Now()
+			for range 3 {
+				release, err := p.Acquire(context.Background(), tt.host)
+				if err != nil {
+					t.Fatal(err)
+				}
+				release()
+			}
+			if got := clock.Now().Sub(start); got != 2*tt.want {
+				t.Errorf("%s: three requests took %v, want %v", tt.host, got, 2*tt.want)
+			}
+		}
+	})
+}
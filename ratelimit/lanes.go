@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Lanes shares one Limiter between lanes of events with different
+// weights. Waiters queue in their lane, and whenever the Limiter is
+// free the next one is picked by smooth weighted round robin: while
+// every lane has waiters, a lane of weight w gets w of every total
+// weight events, spread out rather than in a run. Heavier lanes go
+// first, but no lane with waiters is ever starved.
+//
+// Lane 0 is the first lane; a lane past the last is the last.
+type Lanes struct {
+	limiter Limiter
+	weights []int
+	clock   Clock
+
+	mu      sync.Mutex
+	queues  [][]*laneWaiter
+	current []int // round robin credit of each lane
+	busy    bool  // a waiter has its turn at the Limiter
+	stats   []LaneStats
+}
+
+type laneWaiter struct {
+	turn chan struct{} // closed when it is the waiter's turn
+}
+
+// LaneStats is how the events of a lane waited so far.
+type LaneStats struct {
+	Events int
+	// Waited is the total time from Wait to the event, and MaxWait
+	// the longest.
+	Waited, MaxWait time.Duration
+}
+
+// NewLanes returns Lanes sharing limiter between one lane per weight.
+// A weight below 1 is 1.
+func NewLanes(limiter Limiter, clock Clock, weights ...int) *Lanes {
+	l := &Lanes{
+		limiter: limiter,
+		clock:   clock,
+		weights: make([]int, len(weights)),
+		queues:  make([][]*laneWaiter, len(weights)),
+		current: make([]int, len(weights)),
+		stats:   make([]LaneStats, len(weights)),
+	}
+	for i, w := range weights {
+		l.weights[i] = max(w, 1)
+	}
+	return l
+}
+
+// Wait queues in lane until it is its turn, then waits for the
+// Limiter. It returns an error if ctx is done first.
+func (l *Lanes) Wait(ctx context.Context, lane int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	lane = min(max(lane, 0), len(l.weights)-1)
+	start := l.clock.Now()
+	w := &laneWaiter{turn: make(chan struct{})}
+	l.mu.Lock()
+	l.queues[lane] = append(l.queues[lane], w)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-w.turn:
+	case <-ctx.Done():
+		l.mu.Lock()
+		if i := slices.Index(l.queues[lane], w); i >= 0 {
+			l.queues[lane] = slices.Delete(l.queues[lane], i, i+1)
+		} else {
+			// The turn came as ctx was done: pass it on.
+			l.busy = false
+			l.dispatch()
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+
+	err := l.limiter.Wait(ctx)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		waited := l.clock.Now().Sub(start)
+		s := &l.stats[lane]
+		s.Events++
+		s.Waited += waited
+		s.MaxWait = max(s.MaxWait, waited)
+	}
+	l.busy = false
+	l.dispatch()
+	return err
+}
+
+// dispatch gives the turn to the next waiter unless someone has it.
+// l.mu must be held.
+func (l *Lanes) dispatch() {
+	if l.busy {
+		return
+	}
+	next, total := -1, 0
+	for i, q := range l.queues {
+		if len(q) == 0 {
+			// An idle lane neither saves up nor owes turns.
+			l.current[i] = 0
+			continue
+		}
+		l.current[i] += l.weights[i]
+		total += l.weights[i]
+		if next < 0 || l.current[i] > l.current[next] {
+			next = i
+		}
+	}
+	if next < 0 {
+		return
+	}
+	l.current[next] -= total
+	w := l.queues[next][0]
+	l.queues[next] = l.queues[next][1:]
+	l.busy = true
+	close(w.turn)
+}
+
+// Lane returns a Limiter whose Wait waits in lane.
+func (l *Lanes) Lane(lane int) Limiter {
+	return laneLimiter{l, lane}
+}
+
+type laneLimiter struct {
+	lanes *Lanes
+	lane  int
+}
+
+func (l laneLimiter) Wait(ctx context.Context) error {
+	return l.lanes.Wait(ctx, l.lane)
+}
+
+// Stats returns the stats of every lane so far.
+func (l *Lanes) Stats() []LaneStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.stats)
+}
+
+// queued returns the number of waiters queued in every lane.
+func (l *Lanes) queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, q := range l.queues {
+		n += len(q)
+	}
+	return n
+}
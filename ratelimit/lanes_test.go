@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// gate is a Limiter that allows one event per value sent on it.
+type gate chan struct{}
+
+func (g gate) Wait(ctx context.Context) error {
+	select {
+	case <-g:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitUntil polls until cond is true.
+func waitUntil(cond func() bool) {
+	for !cond() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// isBusy reports whether a waiter has its turn at l's Limiter.
+func (l *Lanes) isBusy() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.busy
+}
+
+func TestLanesOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		queued  []int // lane of each waiter, in the order they queue
+		want    []int // lanes in the order they get events
+	}{
+		{
+			name:    "equal weights alternate",
+			weights: []int{1, 1},
+			queued:  []int{0, 0, 0, 1, 1, 1},
+			want:    []int{0, 1, 0, 1, 0, 1},
+		},
+		{
+			name:    "three to one",
+			weights: []int{3, 1},
+			queued:  []int{0, 0, 0, 0, 0, 0, 1, 1, 1, 1},
+			want:    []int{0, 0, 1, 0, 0, 0, 1, 0, 1, 1},
+		},
+		{
+			name:    "low lane is not starved",
+			weights: []int{100, 1},
+			queued:  []int{1, 0, 0, 0, 0},
+			want:    []int{0, 0, 0, 0, 1},
+		},
+		{
+			name:    "past the last lane",
+			weights: []int{2, 1},
+			queued:  []int{5, 5, 0, 0},
+			want:    []int{0, 1, 0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := make(gate)
+			l := NewLanes(g, NewFakeClock(epoch), tt.weights...)
+			done := make(chan int)
+			// A first waiter takes the turn and holds it
+			// until the others have queued.
+			go func() {
+				l.Wait(context.Background(), 0)
+				done <- -1
+			}()
+			waitUntil(l.isBusy)
+			for _, lane := range tt.queued {
+				go func() {
+					l.Wait(context.Background(), lane)
+					done <- min(lane, len(tt.weights)-1)
+				}()
+			}
+			waitUntil(func() bool { return l.queued() == len(tt.queued) })
+
+			g <- struct{}{}
+			<-done
+			var got []int
+			for range tt.queued {
+				g <- struct{}{}
+				got = append(got, <-done)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Wait() got order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLanesCancel(t *testing.T) {
+	g := make(gate)
+	l := NewLanes(g, NewFakeClock(epoch), 1, 1)
+	held := make(chan error)
+	go func() { held <- l.Wait(context.Background(), 0) }()
+	waitUntil(l.isBusy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() { cancelled <- l.Wait(ctx, 1) }()
+	waitUntil(func() bool { return l.queued() == 1 })
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Errorf("Wait() got = %v, want %v", err, context.Canceled)
+	}
+	if n := l.queued(); n != 0 {
+		t.Errorf("cancelled waiter still queued: %d queued", n)
+	}
+
+	g <- struct{}{}
+	if err := <-held; err != nil {
+		t.Fatal(err)
+	}
+	next := make(chan error)
+	go func() { next <- l.Wait(context.Background(), 1) }()
+	g <- struct{}{}
+	if err := <-next; err != nil {
+		t.Errorf("Wait() after cancel got = %v, want nil", err)
+	}
+}
+
+func TestLanesStats(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	l := NewLanes(NewInterval(time.Second, clock), clock, 2, 1)
+	clock.Drive(func() {
+		for _, lane := range []int{0, 0, 1} {
+			if err := l.Lane(lane).Wait(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	want := []LaneStats{
+		{Events: 2, Waited: time.Second, MaxWait: time.Second},
+		{Events: 1, Waited: time.Second, MaxWait: time.Second},
+	}
+	if got := l.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() got = %+v, want %+v", got, want)
+	}
+}
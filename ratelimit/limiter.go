@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Limiter paces events: Wait blocks until the next event may happen,
+// or returns an error if ctx is done first.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+var (
+	_ Limiter = (*Interval)(nil)
+	_ Limiter = (*TokenBucket)(nil)
+	_ Limiter = (*LeakyBucket)(nil)
+	_ Limiter = (*SlidingWindow)(nil)
+)
+
+// Interval allows one event per interval, at most: the first at once
+// and every later one an interval after the one before. Unlike a
+// TokenBucket it never saves up time while idle.
+type Interval struct {
+	interval time.Duration
+	clock    Clock
+
+	mu   sync.Mutex
+	next time.Time // earliest time of the next event
+}
+
+// NewInterval returns an Interval allowing one event per interval.
+func NewInterval(interval time.Duration, clock Clock) *Interval {
+	return &Interval{interval: interval, clock: clock}
+}
+
+// Wait reserves the next free slot and sleeps until it. A cancelled
+// Wait does not give its slot back.
+func (l *Interval) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	now := l.clock.Now()
+	at := now
+	if l.next.After(now) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	return l.clock.Sleep(ctx, at.Sub(now))
+}
+
+// ErrBucketFull is returned by LeakyBucket.Wait
+// when the bucket's queue is full.
+var ErrBucketFull = errors.New("ratelimit: bucket full")
+
+// LeakyBucket queues events and lets them out one per interval, so
+// they leave evenly spaced however they arrive. At most capacity
+// events wait in the queue; Wait fails at once with ErrBucketFull
+// rather than join a full queue.
+type LeakyBucket struct {
+	interval time.Duration
+	capacity int
+	clock    Clock
+
+	mu   sync.Mutex
+	next time.Time // time the next event leaves
+}
+
+// NewLeakyBucket returns a LeakyBucket letting out one event per
+// interval and queueing at most capacity. capacity <= 0 means no cap.
+func NewLeakyBucket(interval time.Duration, capacity int, clock Clock) *LeakyBucket {
+	return &LeakyBucket{interval: interval, capacity: capacity, clock: clock}
+}
+
+func (b *LeakyBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	now := b.clock.Now()
+	at := now
+	if b.next.After(now) {
+		at = b.next
+		// Events queued ahead: one per interval until at.
+		if queued := int((at.Sub(now) + b.interval - 1) / b.interval); b.capacity > 0 && queued >= b.capacity {
+			b.mu.Unlock()
+			return ErrBucketFull
+		}
+	}
+	b.next = at.Add(b.interval)
+	b.mu.Unlock()
+	return b.clock.Sleep(ctx, at.Sub(now))
+}
+
+// SlidingWindow allows at most limit events in any window of time.
+// It logs the time of every event in the last window, so it is exact
+// but its memory grows with limit.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+	clock  Clock
+
+	mu  sync.Mutex
+	log []time.Time // oldest first
+}
+
+// NewSlidingWindow returns a SlidingWindow allowing limit events per
+// window. A limit below 1 is 1.
+func NewSlidingWindow(limit int, window time.Duration, clock Clock) *SlidingWindow {
+	return &SlidingWindow{limit: max(limit, 1), window: window, clock: clock}
+}
+
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	for {
+		d := w.take()
+		if d == 0 {
+			return nil
+		}
+		if err := w.clock.Sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// take logs an event and returns 0 if the window has room,
+// otherwise it returns how long until the oldest event leaves it.
+func (w *SlidingWindow) take() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	i := 0
+	for i < len(w.log) && !w.log[i].Add(w.window).After(now) {
+		i++
+	}
+	w.log = w.log[i:]
+	if len(w.log) < w.limit {
+		w.log = append(w.log, now)
+		return 0
+	}
+	return w.log[0].Add(w.window).Sub(now)
+}
@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiters(t *testing.T) {
+	tests := []struct {
+		name     string
+		limiter  func(clock Clock) Limiter
+		interval time.Duration
+		burst    int
+		events   int
+		want     time.Duration // time on the clock after the last event
+	}{
+		{
+			name:     "interval",
+			limiter:  func(clock Clock) Limiter { return NewInterval(time.Second, clock) },
+			interval: time.Second, burst: 1, events: 5, want: 4 * time.Second,
+		},
+		{
+			name:     "token bucket",
+			limiter:  func(clock Clock) Limiter { return NewTokenBucket(time.Second, 3, clock) },
+			interval: time.Second, burst: 3, events: 5, want: 2 * time.Second,
+		},
+		{
+			name:     "leaky bucket",
+			limiter:  func(clock Clock) Limiter { return NewLeakyBucket(time.Second, 0, clock) },
+			interval: time.Second, burst: 1, events: 5, want: 4 * time.Second,
+		},
+		{
+			name:     "sliding window",
+			limiter:  func(clock Clock) Limiter { return NewSlidingWindow(1, time.Second, clock) },
+			interval: time.Second, burst: 1, events: 5, want: 4 * time.Second,
+		},
+		{
+			name:     "no interval",
+			limiter:  func(clock Clock) Limiter { return NewInterval(0, clock) },
+			interval: 0, burst: 1, events: 5, want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(epoch)
+			l := tt.limiter(clock)
+			var times []time.Time
+			clock.Drive(func() {
+				for range tt.events {
+					if err := l.Wait(context.Background()); err != nil {
+						t.Fatal(err)
+					}
+					times = append(times, clock.Now())
+				}
+			})
+			checkSpacing(t, "Wait()", times, epoch, tt.interval, tt.burst)
+			if got := clock.Now().Sub(epoch); got != tt.want {
+				t.Errorf("Wait() took %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitersConcurrent(t *testing.T) {
+	limiters := map[string]func(clock Clock) Limiter{
+		"interval":       func(clock Clock) Limiter { return NewInterval(time.Second, clock) },
+		"token bucket":   func(clock Clock) Limiter { return NewTokenBucket(time.Second, 1, clock) },
+		"leaky bucket":   func(clock Clock) Limiter { return NewLeakyBucket(time.Second, 0, clock) },
+		"sliding window": func(clock Clock) Limiter { return NewSlidingWindow(1, time.Second, clock) },
+	}
+	for name, newLimiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			clock := NewFakeClock(epoch)
+			l := newLimiter(clock)
+			var (
+				mu    sync.Mutex
+				times []time.Time
+				wg    sync.WaitGroup
+			)
+			clock.Drive(func() {
+				for range 20 {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						l.Wait(context.Background())
+						mu.Lock()
+						times = append(times, clock.Now())
+						mu.Unlock()
+					}()
+				}
+				wg.Wait()
+			})
+			checkSpacing(t, "Wait()", times, epoch, time.Second, 1)
+		})
+	}
+}
+
+func TestIntervalIdle(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	l := NewInterval(time.Second, clock)
+	clock.Drive(func() {
+		l.Wait(context.Background())
+		// Unlike a token bucket, idle time is not saved up.
+		clock.Advance(10 * time.Second)
+		start := clock.Now()
+		for range 3 {
+			l.Wait(context.Background())
+		}
+		if got := clock.Now().Sub(start); got != 2*time.Second {
+			t.Errorf("Wait() after idle took %v, want %v", got, 2*time.Second)
+		}
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	w := NewSlidingWindow(3, 10*time.Second, clock)
+	var got []time.Duration
+	clock.Drive(func() {
+		for range 7 {
+			w.Wait(context.Background())
+			got = append(got, clock.Now().Sub(epoch))
+			clock.Advance(time.Second)
+		}
+	})
+	// Three events at 0s, 1s and 2s fill the window; the next three
+	// wait for those to leave it, at 10s, 11s and 12s.
+	want := []time.Duration{0, 1, 2, 10, 11, 12, 20}
+	for i := range want {
+		if got[i] != want[i]*time.Second {
+			t.Errorf("event %d at %v, want %v", i, got[i], want[i]*time.Second)
+		}
+	}
+}
+
+func TestLeakyBucketFull(t *testing.T) {
+	b := NewLeakyBucket(time.Hour, 2, SystemClock)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The second event queues for an hour; its Wait gives up,
+	// but the slot stays taken.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := b.Wait(context.Background()); !errors.Is(err, ErrBucketFull) {
+		t.Errorf("Wait() error = %v, want %v", err, ErrBucketFull)
+	}
+}
+
+func TestLimitersContext(t *testing.T) {
+	limiters := map[string]Limiter{
+		"interval":       NewInterval(time.Hour, SystemClock),
+		"leaky bucket":   NewLeakyBucket(time.Hour, 0, SystemClock),
+		"sliding window": NewSlidingWindow(1, time.Hour, SystemClock),
+	}
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			l.Wait(context.Background())
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
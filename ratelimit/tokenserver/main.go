@@ -0,0 +1,68 @@
+// Tokenserver serves one rate limit to many crawler processes, see
+// ratelimit.Server. Point 01-limit-crawler at it with
+//
+//	CRAWL_LIMITER=unix:/tmp/crawl.sock go run ./01-limit-crawler
+//
+// It stops on SIGINT or SIGTERM, removing its unix socket. A socket
+// left behind by a server that was killed is removed on start.
+package main
+
+import (
+	"context"
+	"flag"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"concurrency/ratelimit"
+)
+
+func main() {
+	network := flag.String("network", "unix", "`network` to listen on, unix or tcp")
+	addr := flag.String("addr", "/tmp/crawl.sock", "`address` to listen on")
+	interval := flag.Duration("interval", time.Second, "time between events")
+	burst := flag.Int("burst", 1, "events allowed in a burst")
+	flag.Parse()
+
+	if *network == "unix" {
+		if err := removeStale(*addr); err != nil {
+			log.Fatal(err)
+		}
+	}
+	ln, err := net.Listen(*network, *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Closing the listener makes Serve return, and removes the socket.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	log.Printf("serving one event per %v, bursts of %d, on %s %s", *interval, *burst, *network, *addr)
+	s := &ratelimit.Server{Limiter: ratelimit.NewTokenBucket(*interval, *burst, ratelimit.SystemClock)}
+	if err := s.Serve(ln); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// removeStale removes the unix socket at path if no server answers on
+// it. A socket with a live server is left alone, for Listen to fail on.
+func removeStale(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode().Type() != fs.ModeSocket {
+		return nil
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil
+	}
+	return os.Remove(path)
+}